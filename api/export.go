@@ -0,0 +1,345 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"postgres"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+	exportFormatXLSX   = "xlsx"
+)
+
+var exportColumns = []string{"id", "projectId", "externalKey", "name", "description", "priority", "removed", "createdAt", "version"}
+
+var exportContentTypes = map[string]string{
+	exportFormatCSV:    "text/csv; charset=utf-8",
+	exportFormatNDJSON: "application/x-ndjson",
+	exportFormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type goodExportRow struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"projectId"`
+	ExternalKey string    `json:"externalKey,omitempty"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Priority    int       `json:"priority"`
+	Removed     bool      `json:"removed"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Version     int       `json:"version"`
+}
+
+func newGoodExportRow(good postgres.Good) goodExportRow {
+	row := goodExportRow{
+		ID:          good.ID,
+		ProjectID:   good.ProjectID,
+		Name:        good.Name,
+		Description: good.Description,
+		Priority:    good.Priority,
+		Removed:     good.Removed,
+		CreatedAt:   good.CreatedAt,
+		Version:     good.Version,
+	}
+	if good.ExternalKey != nil {
+		row.ExternalKey = *good.ExternalKey
+	}
+
+	return row
+}
+
+// значения в порядке exportColumns
+func (row goodExportRow) values() []string {
+	return []string{
+		strconv.Itoa(row.ID),
+		strconv.Itoa(row.ProjectID),
+		row.ExternalKey,
+		row.Name,
+		row.Description,
+		strconv.Itoa(row.Priority),
+		strconv.FormatBool(row.Removed),
+		row.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.Itoa(row.Version),
+	}
+}
+
+// goodsExportWriter пишет товары в ответ по одному, Close дописывает окончание файла
+type goodsExportWriter interface {
+	Write(row goodExportRow) error
+	Close() error
+}
+
+func newGoodsExportWriter(format string, w io.Writer) (goodsExportWriter, error) {
+	switch format {
+	case exportFormatCSV:
+		return newCSVExportWriter(w)
+	case exportFormatNDJSON:
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}, nil
+	case exportFormatXLSX:
+		return newXLSXExportWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown export format [%s]", format)
+	}
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	writer := csv.NewWriter(w)
+	err := writer.Write(exportColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &csvExportWriter{writer: writer}, nil
+}
+
+func (c *csvExportWriter) Write(row goodExportRow) error {
+	return c.writer.Write(row.values())
+}
+
+func (c *csvExportWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonExportWriter) Write(row goodExportRow) error {
+	return n.encoder.Encode(row)
+}
+
+func (n *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// xlsxExportWriter собирает минимальную книгу из одного листа. Лист пишется в zip потоком,
+// строки хранятся как inline strings, чтобы не копить общую таблицу строк в памяти
+type xlsxExportWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	rows    int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="goods" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+func newXLSXExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	archive := zip.NewWriter(w)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		partWriter, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.WriteString(partWriter, part.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// лист должен быть последним файлом архива: zip пишет файлы только по очереди
+	sheetWriter, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	x := &xlsxExportWriter{
+		archive: archive,
+		sheet:   bufio.NewWriter(sheetWriter),
+	}
+
+	x.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	x.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	header := make([]interface{}, 0, len(exportColumns))
+	for _, column := range exportColumns {
+		header = append(header, column)
+	}
+
+	return x, x.writeRow(header)
+}
+
+func (x *xlsxExportWriter) Write(row goodExportRow) error {
+	return x.writeRow([]interface{}{
+		row.ID,
+		row.ProjectID,
+		row.ExternalKey,
+		row.Name,
+		row.Description,
+		row.Priority,
+		row.Removed,
+		row.CreatedAt.UTC().Format(time.RFC3339),
+		row.Version,
+	})
+}
+
+// writeRow пишет строку листа: числа и bool - типизированными ячейками, остальное - строками
+func (x *xlsxExportWriter) writeRow(cells []interface{}) error {
+	x.rows++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.rows)
+
+	for _, cell := range cells {
+		switch value := cell.(type) {
+		case int:
+			fmt.Fprintf(x.sheet, `<c t="n"><v>%d</v></c>`, value)
+		case bool:
+			flag := 0
+			if value {
+				flag = 1
+			}
+			fmt.Fprintf(x.sheet, `<c t="b"><v>%d</v></c>`, flag)
+		default:
+			x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			err := xml.EscapeText(x.sheet, []byte(fmt.Sprint(value)))
+			if err != nil {
+				return err
+			}
+			x.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxExportWriter) Close() error {
+	_, err := x.sheet.WriteString(`</sheetData></worksheet>`)
+	if err != nil {
+		return err
+	}
+
+	err = x.sheet.Flush()
+	if err != nil {
+		return err
+	}
+
+	return x.archive.Close()
+}
+
+// GOODS EXPORT
+// Выгружает все товары проекта с теми же фильтрами и сортировкой, что и список, без пагинации и кеша
+type goodsExportParams struct {
+	Format string `validate:"required,oneof=csv ndjson xlsx"`
+}
+
+func goodsExport(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling goods export request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+
+	queryValues := r.URL.Query()
+	params := goodsExportParams{
+		Format: queryValues.Get("format"),
+	}
+
+	err := validateRequest(params)
+	if err != nil {
+		logrus.Errorf("error validate request [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	projectId, err := strconv.Atoi(queryValues.Get("projectId"))
+	if err != nil {
+		logrus.Errorf("error convert projecIdParam [%s] to int [%s]", queryValues.Get("projectId"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	filter, err := parseGoodsFilter(queryValues, postgres.GoodsSortID)
+	if err != nil {
+		logrus.Errorf("error parse goods filter [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	// запрос выполняется до заголовков: если postgres недоступен, клиент получает обычную ошибку
+	export, err := postgres.OpenGoodsExport(projectId, filter)
+	if err != nil {
+		logrus.Errorf("error opening goods export of projectID=[%d] [%s]", projectId, err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+	defer export.Close()
+
+	w.Header().Set("Content-Type", exportContentTypes[params.Format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="goods-%d.%s"`, projectId, params.Format))
+
+	// после первой записи статус ответа уже не поменять: при ошибке соединение обрывается,
+	// чтобы клиент не принял недописанный файл за целый
+	writer, err := newGoodsExportWriter(params.Format, w)
+	if err != nil {
+		logrus.Errorf("error starting goods export of projectID=[%d] [%s]", projectId, err.Error())
+		panic(http.ErrAbortHandler)
+	}
+
+	exported := 0
+	err = export.Each(func(good postgres.Good) error {
+		exported++
+		return writer.Write(newGoodExportRow(good))
+	})
+	if err != nil {
+		logrus.Errorf("error exporting goods of projectID=[%d] after [%d] rows [%s]", projectId, exported, err.Error())
+		panic(http.ErrAbortHandler)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		logrus.Errorf("error finishing goods export of projectID=[%d] [%s]", projectId, err.Error())
+		panic(http.ErrAbortHandler)
+	}
+
+	logrus.Infof("successfully exported [%d] goods of projectID=[%d] as [%s]", exported, projectId, params.Format)
+}
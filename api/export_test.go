@@ -0,0 +1,182 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testExportRows = []goodExportRow{
+	{
+		ID:          1,
+		ProjectID:   7,
+		ExternalKey: "tea",
+		Name:        `Чай "Ёлка", зеленый`,
+		Description: "<b>&</b>\nвторая строка",
+		Priority:    1,
+		CreatedAt:   time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Version:     3,
+	},
+	{
+		ID:        2,
+		ProjectID: 7,
+		Name:      "Coffee",
+		Priority:  2,
+		Removed:   true,
+		CreatedAt: time.Date(2024, 3, 2, 11, 30, 0, 0, time.UTC),
+		Version:   1,
+	},
+}
+
+// writeTestExport пишет testExportRows в буфер в формате format
+func writeTestExport(t *testing.T, format string) []byte {
+	t.Helper()
+
+	buffer := bytes.Buffer{}
+	writer, err := newGoodsExportWriter(format, &buffer)
+	if err != nil {
+		t.Fatalf("error creating [%s] writer [%s]", format, err.Error())
+	}
+
+	for _, row := range testExportRows {
+		err = writer.Write(row)
+		if err != nil {
+			t.Fatalf("error writing [%s] row [%s]", format, err.Error())
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("error closing [%s] writer [%s]", format, err.Error())
+	}
+
+	return buffer.Bytes()
+}
+
+func TestCSVExportWriter(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(writeTestExport(t, exportFormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatalf("error reading exported csv [%s]", err.Error())
+	}
+
+	want := [][]string{exportColumns}
+	for _, row := range testExportRows {
+		want = append(want, row.values())
+	}
+
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("csv records [%q], want [%q]", records, want)
+	}
+}
+
+func TestNDJSONExportWriter(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(string(writeTestExport(t, exportFormatNDJSON)), "\n"), "\n")
+	if len(lines) != len(testExportRows) {
+		t.Fatalf("ndjson has [%d] lines, want [%d]", len(lines), len(testExportRows))
+	}
+
+	for i, line := range lines {
+		row := goodExportRow{}
+		err := json.Unmarshal([]byte(line), &row)
+		if err != nil {
+			t.Fatalf("error decoding ndjson line [%d] [%s]", i+1, err.Error())
+		}
+
+		if !reflect.DeepEqual(row, testExportRows[i]) {
+			t.Errorf("ndjson line [%d] is [%+v], want [%+v]", i+1, row, testExportRows[i])
+		}
+	}
+}
+
+// xlsxSheet - ячейки листа в том виде, в котором их пишет xlsxExportWriter
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXExportWriter(t *testing.T) {
+	data := writeTestExport(t, exportFormatXLSX)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("error opening exported xlsx [%s]", err.Error())
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if files[name] == nil {
+			t.Fatalf("xlsx has no [%s]", name)
+		}
+	}
+
+	file, err := files["xl/worksheets/sheet1.xml"].Open()
+	if err != nil {
+		t.Fatalf("error opening sheet [%s]", err.Error())
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("error reading sheet [%s]", err.Error())
+	}
+
+	sheet := xlsxSheet{}
+	err = xml.Unmarshal(content, &sheet)
+	if err != nil {
+		t.Fatalf("error parsing sheet [%s]", err.Error())
+	}
+
+	// числа и bool - типизированные ячейки, остальное - inline строки
+	want := [][]string{exportColumns}
+	for _, row := range testExportRows {
+		values := row.values()
+		values[6] = "0"
+		if row.Removed {
+			values[6] = "1"
+		}
+		want = append(want, values)
+	}
+	wantTypes := []string{"n", "n", "inlineStr", "inlineStr", "inlineStr", "n", "b", "inlineStr", "n"}
+
+	if len(sheet.Rows) != len(want) {
+		t.Fatalf("sheet has [%d] rows, want [%d]", len(sheet.Rows), len(want))
+	}
+
+	for i, row := range sheet.Rows {
+		if row.R != i+1 {
+			t.Errorf("row [%d] has number [%d]", i+1, row.R)
+		}
+
+		got := []string{}
+		for j, cell := range row.Cells {
+			value := cell.Value
+			if cell.Type == "inlineStr" {
+				value = cell.Inline
+			}
+			got = append(got, value)
+
+			if i > 0 && cell.Type != wantTypes[j] {
+				t.Errorf("row [%d] cell [%d] has type [%s], want [%s]", i+1, j+1, cell.Type, wantTypes[j])
+			}
+		}
+
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("row [%d] is [%q], want [%q]", i+1, got, want[i])
+		}
+	}
+}
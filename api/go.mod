@@ -1,8 +1,9 @@
-module main
+module api
 
 go 1.21
 
 require (
+	clickhousedb v0.0.0-00010101000000-000000000000
 	natsq v0.0.0-00010101000000-000000000000
 	postgres v0.0.0-00010101000000-000000000000
 	redisdb v0.0.0-00010101000000-000000000000
@@ -16,31 +17,47 @@ require (
 
 require (
 	common v0.0.0-00010101000000-000000000000 // indirect
+	event v0.0.0-00010101000000-000000000000 // indirect
+	github.com/ClickHouse/ch-go v0.61.3 // indirect
+	github.com/ClickHouse/clickhouse-go/v2 v2.20.0 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
+	github.com/go-faster/city v1.0.1 // indirect
+	github.com/go-faster/errors v0.7.1 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/go-redis/redis v6.15.9+incompatible // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
 	github.com/jackc/pgx/v5 v5.4.3 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
-	github.com/klauspost/compress v1.17.2 // indirect
+	github.com/klauspost/compress v1.17.7 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/lib/pq v1.10.9 // indirect
 	github.com/nats-io/nats.go v1.33.1 // indirect
 	github.com/nats-io/nkeys v0.4.7 // indirect
 	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/paulmach/orb v0.11.1 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/segmentio/asm v1.2.0 // indirect
+	github.com/shopspring/decimal v1.3.1 // indirect
+	go.opentelemetry.io/otel v1.24.0 // indirect
+	go.opentelemetry.io/otel/trace v1.24.0 // indirect
 	golang.org/x/crypto v0.19.0 // indirect
-	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 	gorm.io/driver/postgres v1.5.6 // indirect
 	gorm.io/gorm v1.25.7 // indirect
 )
 
+replace clickhousedb => ../clickhousedb
+
 replace common => ../common
 
 replace postgres => ../postgres
@@ -48,3 +65,5 @@ replace postgres => ../postgres
 replace redisdb => ../redisdb
 
 replace natsq => ../natsq
+
+replace event => ../event
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"postgres"
+	"redisdb"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator"
@@ -17,6 +23,8 @@ var validate *validator.Validate
 const (
 	errInternal     = "errors.internal"
 	errGoodNotFound = "errors.good.notFound"
+	errGoodConflict = "errors.good.versionConflict"
+	errPurgeRunning = "errors.goods.purgeRunning"
 	errWrongParams  = "wrong.params"
 )
 
@@ -70,9 +78,15 @@ func pingHandler(writer http.ResponseWriter, request *http.Request) {
 	writeResponse(writer, pingResponse, 200)
 }
 
+// REDIS METRICS
+// Отдает только счетчики redis: общий expvar раскрывает cmdline и memstats процесса
+func redisMetrics(writer http.ResponseWriter, request *http.Request) {
+	writeResponse(writer, redisdb.Metrics(), 200)
+}
+
 // GOOD CREATE
 type goodCreateRequest struct {
-	Name string
+	Name string `validate:"required,max=100"`
 }
 
 type goodCreateUpdateResponse struct {
@@ -84,6 +98,7 @@ type goodCreateUpdateResponse struct {
 	Priority    int       `json:"priority"`
 	Removed     bool      `json:"removed"`
 	CreatedAt   time.Time `json:"createdAt"`
+	Version     int       `json:"version"`
 }
 
 func goodCreate(w http.ResponseWriter, r *http.Request) {
@@ -144,12 +159,94 @@ func (resp *goodCreateUpdateResponse) New(good postgres.Good) {
 	resp.Priority = good.Priority
 	resp.Removed = good.Removed
 	resp.CreatedAt = good.CreatedAt
+	resp.Version = good.Version
+}
+
+// etag товара меняется вместе с его версией
+func goodETag(good postgres.Good) string {
+	return fmt.Sprintf(`"%d-%d"`, good.ID, good.Version)
+}
+
+// GOOD GET
+func goodGet(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling good get request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	resp := goodCreateUpdateResponse{}
+
+	queryValues := r.URL.Query()
+	id, err := strconv.Atoi(queryValues.Get("id"))
+	if err != nil {
+		logrus.Errorf("error convert id [%s] to int [%s]", queryValues.Get("id"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	projectId, err := strconv.Atoi(queryValues.Get("projectId"))
+	if err != nil {
+		logrus.Errorf("error convert projecIdParam [%s] to int [%s]", queryValues.Get("projectId"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	good := postgres.Good{
+		ID:        id,
+		ProjectID: projectId,
+	}
+	err = good.Get()
+	if errors.Is(err, postgres.ErrNotFound) {
+		badResponse.Error = errGoodNotFound
+		writeResponse(w, badResponse, 404)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error getting good with id=[%d], projectID=[%d] [%s]", id, projectId, err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	etag := goodETag(good)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		logrus.Infof("good with id [%d], projectID=[%d] not modified", id, projectId)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	resp.New(good)
+	logrus.Infof("successfully got good with id [%d], projectID=[%d]", id, projectId)
+	writeResponse(w, resp, 200)
+}
+
+// etagMatches проверяет заголовок If-None-Match: список etag через запятую или "*"
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
 }
 
 // GOOD UPDATE
+// Меняются только переданные поля, отсутствующие в теле запроса остаются как есть.
+// Version - ожидаемая версия товара, ее же можно передать etag'ом в If-Match
 type goodUpdateRequest struct {
-	Name        string
-	Description string
+	Name        *string `validate:"omitempty,min=1,max=100"`
+	Description *string `validate:"omitempty,max=255"`
+	Version     *int
+}
+
+type goodConflictResponse struct {
+	Success bool                     `json:"success"`
+	Error   string                   `json:"error"`
+	Good    goodCreateUpdateResponse `json:"good"`
 }
 
 func goodUpdate(w http.ResponseWriter, r *http.Request) {
@@ -193,11 +290,39 @@ func goodUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedVersion := req.Version
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && expectedVersion == nil {
+		version, err := parseGoodETag(ifMatch, id)
+		if err != nil {
+			logrus.Errorf("error parse If-Match [%s] [%s]", ifMatch, err.Error())
+			badResponse.Error = errWrongParams
+			writeResponse(w, badResponse, 500)
+			return
+		}
+		expectedVersion = &version
+	}
+
 	good := postgres.Good{
 		ID:        id,
 		ProjectID: projectId,
 	}
-	err = good.Update(req.Name, req.Description)
+	err = good.Update(req.Name, req.Description, expectedVersion)
+	if errors.Is(err, postgres.ErrNotFound) {
+		badResponse.Error = errGoodNotFound
+		writeResponse(w, badResponse, 404)
+		return
+	}
+	if errors.Is(err, postgres.ErrVersionConflict) {
+		conflictResponse := goodConflictResponse{
+			Success: false,
+			Error:   errGoodConflict,
+		}
+		conflictResponse.Good.New(good)
+		conflictResponse.Good.Success = false
+		w.Header().Set("ETag", goodETag(good))
+		writeResponse(w, conflictResponse, 409)
+		return
+	}
 	if err != nil {
 		logrus.Errorf("error updating good with id=[%d], projectID=[%d] [%s]", id, projectId, err.Error())
 		writeResponse(w, badResponse, 500)
@@ -206,9 +331,22 @@ func goodUpdate(w http.ResponseWriter, r *http.Request) {
 
 	resp.New(good)
 	logrus.Infof("successfully updated good with id [%d], projectID=[%d]", id, projectId)
+	w.Header().Set("ETag", goodETag(good))
 	writeResponse(w, resp, 200)
 }
 
+// parseGoodETag достает версию из etag вида "<id>-<version>"
+func parseGoodETag(etag string, id int) (int, error) {
+	etag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(etag), "W/"), `"`)
+
+	idPart, versionPart, ok := strings.Cut(etag, "-")
+	if !ok || idPart != strconv.Itoa(id) {
+		return 0, fmt.Errorf("etag [%s] doesnt belong to good [%d]", etag, id)
+	}
+
+	return strconv.Atoi(versionPart)
+}
+
 // GOOD DELETE
 type goodDeleteResponse struct {
 	Success   bool `json:"success"`
@@ -265,11 +403,322 @@ func (resp *goodDeleteResponse) New(good postgres.Good) {
 	resp.Removed = good.Removed
 }
 
-// GOODS LIST
-type goodsListResponse struct {
+// GOOD RESTORE
+func goodRestore(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling good restore request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	resp := goodCreateUpdateResponse{}
+
+	queryValues := r.URL.Query()
+	id, err := strconv.Atoi(queryValues.Get("id"))
+	if err != nil {
+		logrus.Errorf("error convert id [%s] to int [%s]", queryValues.Get("id"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	projectId, err := strconv.Atoi(queryValues.Get("projectId"))
+	if err != nil {
+		logrus.Errorf("error convert projecIdParam [%s] to int [%s]", queryValues.Get("projectId"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	good := postgres.Good{
+		ID:        id,
+		ProjectID: projectId,
+	}
+	err = good.Restore()
+	if errors.Is(err, postgres.ErrNotFound) {
+		badResponse.Error = errGoodNotFound
+		writeResponse(w, badResponse, 404)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error restoring good with id=[%d], projectID=[%d] [%s]", id, projectId, err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp.New(good)
+	logrus.Infof("successfully restored good with id [%d], projectID=[%d]", id, projectId)
+	w.Header().Set("ETag", goodETag(good))
+	writeResponse(w, resp, 200)
+}
+
+// GOODS PURGE
+// Запускает окончательное удаление давно удаленных товаров, не дожидаясь фоновой очистки
+type goodsPurgeResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	Purged  int    `json:"purged"`
+}
+
+func goodsPurge(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling goods purge request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+
+	purged, err := postgres.PurgeRemovedGoods()
+	if errors.Is(err, postgres.ErrPurgeRunning) {
+		badResponse.Error = errPurgeRunning
+		writeResponse(w, badResponse, 409)
+		return
+	}
+	if err != nil {
+		// товары, удаленные до ошибки, уже в архиве: их количество возвращается вместе с ошибкой
+		logrus.Errorf("error purging removed goods after [%d] purged [%s]", purged, err.Error())
+		resp := goodsPurgeResponse{
+			Success: false,
+			Error:   errInternal,
+			Purged:  purged,
+		}
+		writeResponse(w, resp, 500)
+		return
+	}
+
+	resp := goodsPurgeResponse{
+		Success: true,
+		Purged:  purged,
+	}
+	logrus.Infof("successfully purged [%d] removed goods", purged)
+	writeResponse(w, resp, 200)
+}
+
+// GOODS BULK CREATE
+// mode=atomic (по умолчанию): при ошибке хотя бы в одном товаре не создается ни один.
+// mode=partial: создаются все корректные товары, ошибки возвращаются по индексам.
+// За один запрос - не больше 1000 товаров
+type goodsBulkCreateItem struct {
+	Name        string `validate:"required,max=100"`
+	Description string `validate:"max=255"`
+}
+
+type goodsBulkCreateRequest struct {
+	Mode  string                `validate:"omitempty,oneof=atomic partial"`
+	Goods []goodsBulkCreateItem `validate:"required,min=1,max=1000"`
+}
+
+type goodsBulkItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type goodsBulkCreateResponse struct {
 	Success bool                       `json:"success"`
-	Meta    meta                       `json:"meta"`
+	Error   string                     `json:"error,omitempty"`
 	Goods   []goodCreateUpdateResponse `json:"goods"`
+	Errors  []goodsBulkItemError       `json:"errors"`
+}
+
+func goodsBulkCreate(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling goods bulk create request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	req := goodsBulkCreateRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logrus.Errorf("error decode request body [%s]", err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	err = validateRequest(req)
+	if err != nil {
+		logrus.Errorf("error validate request [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	queryValues := r.URL.Query()
+	projectId, err := strconv.Atoi(queryValues.Get("projectId"))
+	if err != nil {
+		logrus.Errorf("error convert projecIdParam [%s] to int [%s]", queryValues.Get("projectId"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp := goodsBulkCreateResponse{
+		Goods:  []goodCreateUpdateResponse{},
+		Errors: []goodsBulkItemError{},
+	}
+
+	goods := postgres.GoodSlice{}
+	for i, item := range req.Goods {
+		err = validateRequest(item)
+		if err != nil {
+			resp.Errors = append(resp.Errors, goodsBulkItemError{Index: i, Error: errWrongParams})
+			continue
+		}
+
+		goods = append(goods, postgres.Good{
+			Name:        item.Name,
+			Description: item.Description,
+		})
+	}
+
+	if len(resp.Errors) > 0 && req.Mode != "partial" {
+		logrus.Errorf("error validate [%d] of [%d] goods, nothing created", len(resp.Errors), len(req.Goods))
+		resp.Error = errWrongParams
+		writeResponse(w, resp, 500)
+		return
+	}
+
+	err = goods.Create(projectId)
+	if err != nil {
+		logrus.Errorf("error creating [%d] goods in projectID=[%d] [%s]", len(goods), projectId, err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp.New(goods)
+	logrus.Infof("successfully created [%d] goods in projectID=[%d], [%d] skipped", len(goods), projectId, len(resp.Errors))
+	writeResponse(w, resp, 200)
+}
+
+func (resp *goodsBulkCreateResponse) New(goods postgres.GoodSlice) {
+	resp.Success = true
+
+	for _, good := range goods {
+		resp.Goods = append(resp.Goods, goodCreateUpdateResponse{
+			ID:          good.ID,
+			ProjectID:   good.ProjectID,
+			Name:        good.Name,
+			Description: good.Description,
+			Priority:    good.Priority,
+			Removed:     good.Removed,
+			CreatedAt:   good.CreatedAt,
+			Version:     good.Version,
+		})
+	}
+}
+
+// GOODS BULK DELETE / RESTORE
+// Изменение применяется ко всем товарам атомарно, результат возвращается по каждому id
+const (
+	goodsBulkStatusChanged   = "changed"
+	goodsBulkStatusUnchanged = "unchanged"
+	goodsBulkStatusNotFound  = "notFound"
+)
+
+type goodsBulkIDsRequest struct {
+	IDs []int `json:"ids" validate:"required,min=1,max=500"`
+}
+
+type goodsBulkIDResult struct {
+	ID      int    `json:"id"`
+	Status  string `json:"status"`
+	Removed bool   `json:"removed"`
+}
+
+type goodsBulkIDsResponse struct {
+	Success bool                `json:"success"`
+	Results []goodsBulkIDResult `json:"results"`
+}
+
+func goodsBulkDelete(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling goods bulk delete request...")
+	goodsBulkSetRemoved(w, r, true)
+}
+
+func goodsBulkRestore(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling goods bulk restore request...")
+	goodsBulkSetRemoved(w, r, false)
+}
+
+func goodsBulkSetRemoved(w http.ResponseWriter, r *http.Request, removed bool) {
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	req := goodsBulkIDsRequest{}
+	resp := goodsBulkIDsResponse{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logrus.Errorf("error decode request body [%s]", err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	err = validateRequest(req)
+	if err != nil {
+		logrus.Errorf("error validate request [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	queryValues := r.URL.Query()
+	projectId, err := strconv.Atoi(queryValues.Get("projectId"))
+	if err != nil {
+		logrus.Errorf("error convert projecIdParam [%s] to int [%s]", queryValues.Get("projectId"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	goods := postgres.GoodSlice{}
+	var changed map[int]bool
+	if removed {
+		changed, err = goods.Delete(projectId, req.IDs)
+	} else {
+		changed, err = goods.Restore(projectId, req.IDs)
+	}
+	if err != nil {
+		logrus.Errorf("error setting removed=[%t] for goods %v of projectID=[%d] [%s]", removed, req.IDs, projectId, err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp.New(req.IDs, goods, changed)
+	logrus.Infof("successfully set removed=[%t] for [%d] of [%d] goods of projectID=[%d]", removed, len(changed), len(req.IDs), projectId)
+	writeResponse(w, resp, 200)
+}
+
+func (resp *goodsBulkIDsResponse) New(ids []int, goods postgres.GoodSlice, changed map[int]bool) {
+	resp.Success = true
+
+	found := map[int]postgres.Good{}
+	for _, good := range goods {
+		found[good.ID] = good
+	}
+
+	results := []goodsBulkIDResult{}
+	for _, id := range ids {
+		good, ok := found[id]
+		switch {
+		case !ok:
+			results = append(results, goodsBulkIDResult{ID: id, Status: goodsBulkStatusNotFound})
+		case changed[id]:
+			results = append(results, goodsBulkIDResult{ID: id, Status: goodsBulkStatusChanged, Removed: good.Removed})
+		default:
+			results = append(results, goodsBulkIDResult{ID: id, Status: goodsBulkStatusUnchanged, Removed: good.Removed})
+		}
+	}
+
+	resp.Results = results
+}
+
+// GOODS LIST
+type goodsListResponse struct {
+	Success    bool                       `json:"success"`
+	Meta       meta                       `json:"meta"`
+	Goods      []goodCreateUpdateResponse `json:"goods"`
+	Cursor     *string                    `json:"cursor,omitempty"`
+	NextCursor *string                    `json:"nextCursor,omitempty"`
 }
 
 type meta struct {
@@ -314,29 +763,95 @@ func goodsList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	projectId, err := strconv.Atoi(queryValues.Get("projectId"))
+	if err != nil {
+		logrus.Errorf("error convert projecIdParam [%s] to int [%s]", queryValues.Get("projectId"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
 	goods := postgres.GoodSlice{}
-	total, err := goods.Many(limit, offset)
+
+	// cursor включает keyset-пагинацию (пустой cursor - первая страница), без него работает limit/offset
+	cursorParams, cursorMode := queryValues["cursor"]
+
+	defaultSort := postgres.GoodsSortID
+	if cursorMode {
+		defaultSort = postgres.GoodsSortPriority
+	}
+
+	filter, err := parseGoodsFilter(queryValues, defaultSort)
+	if err != nil {
+		logrus.Errorf("error parse goods filter [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	if cursorMode {
+		if limit <= 0 {
+			logrus.Errorf("wrong limit [%d] for cursor pagination", limit)
+			badResponse.Error = errWrongParams
+			writeResponse(w, badResponse, 500)
+			return
+		}
+
+		cursor, err := decodeGoodsCursor(cursorParams[0])
+		if err != nil {
+			logrus.Errorf("error decode cursor [%s] [%s]", cursorParams[0], err.Error())
+			badResponse.Error = errWrongParams
+			writeResponse(w, badResponse, 500)
+			return
+		}
+
+		counts, nextCursor, err := goods.ManyByCursor(projectId, limit, cursor, filter)
+		if errors.Is(err, postgres.ErrCursorMismatch) {
+			logrus.Errorf("cursor [%s] doesnt match sorting [%s]", cursorParams[0], err.Error())
+			badResponse.Error = errWrongParams
+			writeResponse(w, badResponse, 500)
+			return
+		}
+		if err != nil {
+			logrus.Errorf("error getting goods by cursor [%s]", err.Error())
+			writeResponse(w, badResponse, 500)
+			return
+		}
+
+		resp.New(goods, counts, limit, 0)
+		resp.Cursor = &cursorParams[0]
+		if nextCursor != nil {
+			encoded, err := encodeGoodsCursor(*nextCursor)
+			if err != nil {
+				logrus.Errorf("error encode next cursor [%s]", err.Error())
+				writeResponse(w, badResponse, 500)
+				return
+			}
+			resp.NextCursor = &encoded
+		}
+
+		logrus.Infof("successfully got goods page by cursor of projectID=[%d]", projectId)
+		writeResponse(w, resp, 200)
+		return
+	}
+
+	counts, err := goods.Many(projectId, limit, offset, filter)
 	if err != nil {
 		logrus.Errorf("error getting all goods [%s]", err.Error())
 		writeResponse(w, badResponse, 500)
 		return
 	}
 
-	resp.New(goods, total, limit, offset)
-	logrus.Info("successfully got all goods")
+	resp.New(goods, counts, limit, offset)
+	logrus.Infof("successfully got all goods of projectID=[%d]", projectId)
 	writeResponse(w, resp, 200)
 }
 
-func (resp *goodsListResponse) New(goodSlice postgres.GoodSlice, total, limit, offset int) {
+func (resp *goodsListResponse) New(goodSlice postgres.GoodSlice, counts postgres.GoodsCounts, limit, offset int) {
 	resp.Success = true
 
-	removed := 0
 	goods := []goodCreateUpdateResponse{}
 	for _, good := range goodSlice {
-		if good.Removed {
-			removed++
-		}
-
 		goods = append(goods, goodCreateUpdateResponse{
 			ID:          good.ID,
 			ProjectID:   good.ProjectID,
@@ -345,18 +860,244 @@ func (resp *goodsListResponse) New(goodSlice postgres.GoodSlice, total, limit, o
 			Priority:    good.Priority,
 			Removed:     good.Removed,
 			CreatedAt:   good.CreatedAt,
+			Version:     good.Version,
 		})
 	}
 
 	resp.Meta = meta{
-		Total:   total,
-		Removed: removed,
+		Total:   counts.Total,
+		Removed: counts.Removed,
 		Limit:   limit,
 		Offset:  offset,
 	}
 	resp.Goods = goods
 }
 
+// удаленные товары в список не попадают, если не передан includeRemoved=true или явный фильтр removed
+type goodsListFilterParams struct {
+	Removed        string `validate:"omitempty,oneof=true false"`
+	IncludeRemoved string `validate:"omitempty,oneof=true false"`
+	Name           string `validate:"max=100"`
+	CreatedFrom    string
+	CreatedTo      string
+	Sort           string `validate:"omitempty,oneof=id priority name createdAt"`
+	Order          string `validate:"omitempty,oneof=asc desc"`
+}
+
+// parseGoodsFilter разбирает фильтры списка: removed, name (подстрока), createdFrom/createdTo (RFC3339),
+// sort (id, priority, name, createdAt) и order (asc, desc)
+func parseGoodsFilter(queryValues url.Values, defaultSort string) (postgres.GoodsFilter, error) {
+	params := goodsListFilterParams{
+		Removed:        queryValues.Get("removed"),
+		IncludeRemoved: queryValues.Get("includeRemoved"),
+		Name:           queryValues.Get("name"),
+		CreatedFrom:    queryValues.Get("createdFrom"),
+		CreatedTo:      queryValues.Get("createdTo"),
+		Sort:           queryValues.Get("sort"),
+		Order:          queryValues.Get("order"),
+	}
+
+	err := validateRequest(params)
+	if err != nil {
+		return postgres.GoodsFilter{}, err
+	}
+
+	filter := postgres.GoodsFilter{
+		Name:   params.Name,
+		SortBy: params.Sort,
+		Desc:   params.Order == "desc",
+	}
+
+	if filter.SortBy == "" {
+		filter.SortBy = defaultSort
+	}
+
+	if params.Removed != "" {
+		removed := params.Removed == "true"
+		filter.Removed = &removed
+	} else if params.IncludeRemoved != "true" {
+		removed := false
+		filter.Removed = &removed
+	}
+
+	if params.CreatedFrom != "" {
+		createdFrom, err := time.Parse(time.RFC3339, params.CreatedFrom)
+		if err != nil {
+			return postgres.GoodsFilter{}, err
+		}
+		filter.CreatedFrom = &createdFrom
+	}
+
+	if params.CreatedTo != "" {
+		createdTo, err := time.Parse(time.RFC3339, params.CreatedTo)
+		if err != nil {
+			return postgres.GoodsFilter{}, err
+		}
+		filter.CreatedTo = &createdTo
+	}
+
+	return filter, nil
+}
+
+// курсор для клиента непрозрачен: base64 от json с позицией последнего товара страницы
+func encodeGoodsCursor(cursor postgres.GoodsCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeGoodsCursor(encoded string) (*postgres.GoodsCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := postgres.GoodsCursor{}
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// GOODS SEARCH
+type goodsSearchResponse struct {
+	Success bool              `json:"success"`
+	Meta    goodsSearchMeta   `json:"meta"`
+	Goods   []goodSearchEntry `json:"goods"`
+}
+
+type goodsSearchMeta struct {
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type goodSearchEntry struct {
+	goodCreateUpdateResponse
+	Rank      float64   `json:"rank"`
+	Highlight highlight `json:"highlight"`
+}
+
+// highlight - HTML: текст экранирован, найденные слова обернуты в <b></b>
+type highlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type goodsSearchParams struct {
+	Query string `validate:"required,max=255"`
+}
+
+func goodsSearch(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling goods search request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	resp := goodsSearchResponse{}
+	var err error
+
+	queryValues := r.URL.Query()
+
+	params := goodsSearchParams{
+		Query: queryValues.Get("q"),
+	}
+	err = validateRequest(params)
+	if err != nil {
+		logrus.Errorf("error validate request [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	projectId, err := strconv.Atoi(queryValues.Get("projectId"))
+	if err != nil {
+		logrus.Errorf("error convert projecIdParam [%s] to int [%s]", queryValues.Get("projectId"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	limit := 10
+	limitParam := queryValues.Get("limit")
+	if limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil {
+			logrus.Errorf("error convert limit [%s] to int [%s]", limitParam, err.Error())
+			badResponse.Error = errWrongParams
+			writeResponse(w, badResponse, 500)
+			return
+		}
+	}
+
+	offset := 0
+	offsetParam := queryValues.Get("offset")
+	if offsetParam != "" {
+		offset, err = strconv.Atoi(offsetParam)
+		if err != nil {
+			logrus.Errorf("error convert offset [%s] to int [%s]", offsetParam, err.Error())
+			badResponse.Error = errWrongParams
+			writeResponse(w, badResponse, 500)
+			return
+		}
+	}
+
+	includeRemoved := queryValues.Get("includeRemoved") == "true"
+
+	results := postgres.GoodSearchResultSlice{}
+	total, err := results.Search(projectId, params.Query, includeRemoved, limit, offset)
+	if err != nil {
+		logrus.Errorf("error searching goods [%s]", err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp.New(results, total, limit, offset)
+	logrus.Infof("successfully found [%d] goods of projectID=[%d]", total, projectId)
+	writeResponse(w, resp, 200)
+}
+
+func (resp *goodsSearchResponse) New(results postgres.GoodSearchResultSlice, total, limit, offset int) {
+	resp.Success = true
+
+	goods := []goodSearchEntry{}
+	for _, result := range results {
+		goods = append(goods, goodSearchEntry{
+			goodCreateUpdateResponse: goodCreateUpdateResponse{
+				ID:          result.ID,
+				ProjectID:   result.ProjectID,
+				Name:        result.Name,
+				Description: result.Description,
+				Priority:    result.Priority,
+				Removed:     result.Removed,
+				CreatedAt:   result.CreatedAt,
+				Version:     result.Version,
+			},
+			Rank: result.Rank,
+			Highlight: highlight{
+				Name:        result.NameHighlight,
+				Description: result.DescriptionHighlight,
+			},
+		})
+	}
+
+	resp.Meta = goodsSearchMeta{
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	resp.Goods = goods
+}
+
 // GOOD REPRIORITIZE
 type goodReprioritizeRequest struct {
 	NewPriority int
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"postgres"
+	"testing"
+	"time"
+)
+
+func TestGoodsCursorRoundTrip(t *testing.T) {
+	cursors := []postgres.GoodsCursor{
+		{SortBy: postgres.GoodsSortID, ID: 1},
+		{SortBy: postgres.GoodsSortPriority, Desc: true, ID: 42, Priority: 17},
+		{SortBy: postgres.GoodsSortName, ID: 5, Name: `ёлка "зеленая" & <co>`},
+		{SortBy: postgres.GoodsSortCreatedAt, Desc: true, ID: 9, CreatedAt: time.Date(2024, 3, 1, 12, 30, 15, 123456000, time.UTC)},
+	}
+
+	for _, cursor := range cursors {
+		encoded, err := encodeGoodsCursor(cursor)
+		if err != nil {
+			t.Fatalf("error encoding cursor [%+v] [%s]", cursor, err.Error())
+		}
+
+		decoded, err := decodeGoodsCursor(encoded)
+		if err != nil {
+			t.Fatalf("error decoding cursor [%s] [%s]", encoded, err.Error())
+		}
+
+		if decoded == nil || !decoded.CreatedAt.Equal(cursor.CreatedAt) {
+			t.Fatalf("cursor [%+v] came back as [%+v]", cursor, decoded)
+		}
+
+		decoded.CreatedAt = cursor.CreatedAt
+		if *decoded != cursor {
+			t.Errorf("cursor [%+v] came back as [%+v]", cursor, *decoded)
+		}
+	}
+}
+
+func TestDecodeGoodsCursor(t *testing.T) {
+	cursor, err := decodeGoodsCursor("")
+	if err != nil || cursor != nil {
+		t.Errorf("empty cursor: [%+v] [%v], want first page", cursor, err)
+	}
+
+	for _, encoded := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("{broken"))} {
+		_, err := decodeGoodsCursor(encoded)
+		if err == nil {
+			t.Errorf("cursor [%s] decoded without error", encoded)
+		}
+	}
+}
+
+func TestGoodsListCursorMismatch(t *testing.T) {
+	initValidator()
+
+	cursor, err := encodeGoodsCursor(postgres.GoodsCursor{SortBy: postgres.GoodsSortName, ID: 3, Name: "b"})
+	if err != nil {
+		t.Fatalf("error encoding cursor [%s]", err.Error())
+	}
+
+	// курсор от сортировки по name нельзя продолжить с сортировкой по priority
+	r := httptest.NewRequest("GET", "/api/goods/list?projectId=1&limit=5&sort=priority&cursor="+cursor, nil)
+	w := httptest.NewRecorder()
+	goodsList(w, r)
+
+	resp := badResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("error decoding response [%s] [%s]", w.Body.String(), err.Error())
+	}
+
+	if w.Code != 500 || resp.Error != errWrongParams {
+		t.Errorf("response [%d] [%s], want [500] [%s]", w.Code, w.Body.String(), errWrongParams)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"postgres"
+	"redisdb"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	importFormatCSV    = "csv"
+	importFormatNDJSON = "ndjson"
+
+	errImportBadRow = "errors.import.badRow"
+)
+
+const importUsage = "usage: main import -project <id> [-format csv|ndjson] <file|->"
+
+// goodsImportRow - строка импорта. В CSV колонки ищутся по заголовку. description и priority
+// необязательны: без description описание существующего товара не меняется
+type goodsImportRow struct {
+	ExternalKey string  `json:"externalKey" validate:"required,max=100"`
+	Name        string  `json:"name" validate:"required,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=255"`
+	Priority    *int    `json:"priority" validate:"omitempty,min=1"`
+}
+
+type goodsImportRowError struct {
+	Row         int    `json:"row"`
+	ExternalKey string `json:"externalKey,omitempty"`
+	Error       string `json:"error"`
+	Details     string `json:"details,omitempty"`
+}
+
+type goodsImportReport struct {
+	Success bool                  `json:"success"`
+	Error   string                `json:"error,omitempty"`
+	Rows    int                   `json:"rows"`
+	Created int                   `json:"created"`
+	Updated int                   `json:"updated"`
+	Failed  int                   `json:"failed"`
+	Errors  []goodsImportRowError `json:"errors"`
+}
+
+// goodsImportReader читает строки импорта по одной, не загружая файл целиком
+type goodsImportReader interface {
+	// Next возвращает io.EOF, когда строки закончились. Ошибка разбора одной строки не мешает читать следующие
+	Next() (goodsImportRow, error)
+}
+
+// importFileError - файл нельзя читать дальше: нет нужных колонок или сломан поток
+type importFileError struct {
+	err error
+}
+
+func (e importFileError) Error() string {
+	return e.err.Error()
+}
+
+func newGoodsImportReader(format string, r io.Reader) (goodsImportReader, error) {
+	switch format {
+	case importFormatCSV:
+		return newCSVImportReader(r)
+	case importFormatNDJSON:
+		return &ndjsonImportReader{decoder: json.NewDecoder(r)}, nil
+	default:
+		return nil, fmt.Errorf("unknown import format [%s]", format)
+	}
+}
+
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv header [%s]", err.Error())
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	for _, required := range []string{"externalkey", "name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header has no [%s] column", required)
+		}
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvImportReader) Next() (goodsImportRow, error) {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return goodsImportRow{}, err
+	}
+	if err != nil {
+		// ошибки в кавычках и числе полей относятся к строке, остальные ломают весь поток
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return goodsImportRow{}, err
+		}
+		return goodsImportRow{}, importFileError{err: err}
+	}
+
+	row := goodsImportRow{
+		ExternalKey: c.field(record, "externalkey"),
+		Name:        c.field(record, "name"),
+	}
+
+	// пустая ячейка очищает описание, а отсутствие колонки оставляет его как есть
+	if i, ok := c.columns["description"]; ok && i < len(record) {
+		description := strings.TrimSpace(record[i])
+		row.Description = &description
+	}
+
+	if priorityParam := c.field(record, "priority"); priorityParam != "" {
+		priority, err := strconv.Atoi(priorityParam)
+		if err != nil {
+			return row, fmt.Errorf("error convert priority [%s] to int [%s]", priorityParam, err.Error())
+		}
+		row.Priority = &priority
+	}
+
+	return row, nil
+}
+
+func (c *csvImportReader) field(record []string, column string) string {
+	i, ok := c.columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+
+	return strings.TrimSpace(record[i])
+}
+
+type ndjsonImportReader struct {
+	decoder *json.Decoder
+}
+
+func (n *ndjsonImportReader) Next() (goodsImportRow, error) {
+	raw := json.RawMessage{}
+	err := n.decoder.Decode(&raw)
+	if err == io.EOF {
+		return goodsImportRow{}, err
+	}
+	if err != nil {
+		// после синтаксической ошибки decoder не может найти начало следующей строки
+		return goodsImportRow{}, importFileError{err: err}
+	}
+
+	row := goodsImportRow{}
+	err = json.Unmarshal(raw, &row)
+	if err != nil {
+		return goodsImportRow{}, err
+	}
+
+	return row, nil
+}
+
+// upsertGood сохраняет строку импорта, в тестах подменяется, чтобы проверять отчет без базы
+var upsertGood = func(good *postgres.Good, description *string, priority *int) (bool, error) {
+	return good.Upsert(description, priority)
+}
+
+// importGoods построчно загружает товары в проект. Каждая строка сохраняется отдельно,
+// ошибки строк попадают в отчет и не останавливают импорт
+func importGoods(projectID int, reader goodsImportReader) (goodsImportReport, error) {
+	report := goodsImportReport{
+		Errors: []goodsImportRowError{},
+	}
+
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var fileErr importFileError
+		if errors.As(err, &fileErr) {
+			return report, fileErr.err
+		}
+
+		report.Rows++
+		rowError := goodsImportRowError{
+			Row:         report.Rows,
+			ExternalKey: row.ExternalKey,
+		}
+
+		if err == nil {
+			err = validate.Struct(row)
+			rowError.Error = errWrongParams
+		} else {
+			rowError.Error = errImportBadRow
+		}
+		if err != nil {
+			rowError.Details = err.Error()
+			report.Failed++
+			report.Errors = append(report.Errors, rowError)
+			continue
+		}
+
+		good := postgres.Good{
+			ProjectID:   projectID,
+			ExternalKey: &row.ExternalKey,
+			Name:        row.Name,
+		}
+		created, err := upsertGood(&good, row.Description, row.Priority)
+		if err != nil {
+			// текст ошибки postgres остается в логе, клиенту уходит только код
+			logrus.Errorf("error importing row [%d] with externalKey=[%s] to projectID=[%d] [%s]", report.Rows, row.ExternalKey, projectID, err.Error())
+			rowError.Error = errInternal
+			report.Failed++
+			report.Errors = append(report.Errors, rowError)
+			continue
+		}
+
+		if created {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+
+	report.Success = true
+	return report, nil
+}
+
+// GOODS IMPORT
+// Формат берется из параметра format, без него - из Content-Type (application/x-ndjson), по умолчанию csv
+type goodsImportParams struct {
+	Format string `validate:"omitempty,oneof=csv ndjson"`
+}
+
+func goodsImport(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling goods import request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+
+	queryValues := r.URL.Query()
+	params := goodsImportParams{
+		Format: queryValues.Get("format"),
+	}
+
+	err := validateRequest(params)
+	if err != nil {
+		logrus.Errorf("error validate request [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	projectId, err := strconv.Atoi(queryValues.Get("projectId"))
+	if err != nil {
+		logrus.Errorf("error convert projecIdParam [%s] to int [%s]", queryValues.Get("projectId"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	format := params.Format
+	if format == "" {
+		format = importFormatCSV
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson") {
+			format = importFormatNDJSON
+		}
+	}
+
+	reader, err := newGoodsImportReader(format, r.Body)
+	if err != nil {
+		logrus.Errorf("error reading goods import [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	report, err := importGoods(projectId, reader)
+	if err != nil {
+		// строки до сломанного места уже сохранены, отчет о них возвращается вместе с ошибкой
+		logrus.Errorf("error importing goods to projectID=[%d] after [%d] rows [%s]", projectId, report.Rows, err.Error())
+		report.Error = errWrongParams
+		writeResponse(w, report, 500)
+		return
+	}
+
+	logrus.Infof("successfully imported goods to projectID=[%d]: created [%d], updated [%d], failed [%d]", projectId, report.Created, report.Updated, report.Failed)
+	writeResponse(w, report, 200)
+}
+
+// runImportCommand обрабатывает подкоманду "main import ..." и печатает отчет в stdout
+func runImportCommand(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	projectID := flags.Int("project", 0, "id проекта")
+	format := flags.String("format", importFormatCSV, "csv или ndjson")
+	flags.Parse(args)
+
+	if *projectID == 0 || flags.NArg() != 1 {
+		logrus.Error(importUsage)
+		os.Exit(1)
+	}
+
+	input := os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			logrus.Errorf("error opening import file [%s] [%s]", path, err.Error())
+			os.Exit(1)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	postgresConnParams, err := postgres.GetConnectionParams()
+	if err != nil {
+		logrus.Errorf("Error getting Connection Params [%s]", err.Error())
+		os.Exit(1)
+	}
+
+	err = postgres.Connect(postgresConnParams)
+	if err != nil {
+		logrus.Errorf("Error Connect Postgres [%s]", err.Error())
+		os.Exit(1)
+	}
+
+	err = postgres.MigrateUp()
+	if err != nil {
+		logrus.Errorf("Error migrate [up] [%s]", err.Error())
+		os.Exit(1)
+	}
+
+	// кеш сбросит и invalidator api по событиям, здесь redis только ускоряет это
+	redisConnParams, err := redisdb.GetConnectionParams()
+	if err == nil {
+		err = redisdb.OpenConnection(redisConnParams)
+	}
+	if err != nil {
+		logrus.Warnf("Redis is unavailable, importing without cache invalidation [%s]", err.Error())
+	}
+
+	initValidator()
+
+	reader, err := newGoodsImportReader(*format, input)
+	if err != nil {
+		logrus.Errorf("Error reading goods import [%s]", err.Error())
+		os.Exit(1)
+	}
+
+	report, importErr := importGoods(*projectID, reader)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		logrus.Errorf("Error writing import report [%s]", err.Error())
+	}
+
+	if importErr != nil {
+		logrus.Errorf("Error importing goods after [%d] rows [%s]", report.Rows, importErr.Error())
+		os.Exit(1)
+	}
+}
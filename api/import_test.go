@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"postgres"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// readAllRows читает строки до конца файла или до ошибки, ломающей весь файл
+func readAllRows(reader goodsImportReader) ([]goodsImportRow, []error, error) {
+	rows := []goodsImportRow{}
+	rowErrs := []error{}
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			return rows, rowErrs, nil
+		}
+
+		var fileErr importFileError
+		if errors.As(err, &fileErr) {
+			return rows, rowErrs, err
+		}
+
+		rows = append(rows, row)
+		rowErrs = append(rowErrs, err)
+	}
+}
+
+func TestGoodsImportReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		input     string
+		wantRows  []goodsImportRow
+		rowErrs   []bool
+		wantBreak bool
+	}{
+		{
+			name:   "csv header in any case and order",
+			format: importFormatCSV,
+			input:  " Name ,PRIORITY,externalKey\nЧай,3,tea\nCoffee,,coffee\n",
+			wantRows: []goodsImportRow{
+				{ExternalKey: "tea", Name: "Чай", Priority: intPtr(3)},
+				{ExternalKey: "coffee", Name: "Coffee"},
+			},
+			rowErrs: []bool{false, false},
+		},
+		{
+			name:   "csv short record and quoted comma",
+			format: importFormatCSV,
+			input:  "externalKey,name,description\nk1,\"a, b\",\"say \"\"hi\"\"\"\nk2,only name\n",
+			wantRows: []goodsImportRow{
+				{ExternalKey: "k1", Name: "a, b", Description: stringPtr(`say "hi"`)},
+				{ExternalKey: "k2", Name: "only name"},
+			},
+			rowErrs: []bool{false, false},
+		},
+		{
+			name:   "csv empty description clears it",
+			format: importFormatCSV,
+			input:  "externalKey,name,description\nk1,a,\n",
+			wantRows: []goodsImportRow{
+				{ExternalKey: "k1", Name: "a", Description: stringPtr("")},
+			},
+			rowErrs: []bool{false},
+		},
+		{
+			name:   "ndjson without description keeps it",
+			format: importFormatNDJSON,
+			input:  "{\"externalKey\":\"k1\",\"name\":\"a\"}\n{\"externalKey\":\"k2\",\"name\":\"b\",\"description\":\"\"}\n",
+			wantRows: []goodsImportRow{
+				{ExternalKey: "k1", Name: "a"},
+				{ExternalKey: "k2", Name: "b", Description: stringPtr("")},
+			},
+			rowErrs: []bool{false, false},
+		},
+		{
+			name:   "csv bare quote is a row error",
+			format: importFormatCSV,
+			input:  "externalKey,name\nk1,bro\"ken\nk2,ok\n",
+			wantRows: []goodsImportRow{
+				{},
+				{ExternalKey: "k2", Name: "ok"},
+			},
+			rowErrs: []bool{true, false},
+		},
+		{
+			name:   "csv bad priority keeps the key",
+			format: importFormatCSV,
+			input:  "externalKey,name,priority\nk1,a,first\nk2,b,2\n",
+			wantRows: []goodsImportRow{
+				{ExternalKey: "k1", Name: "a"},
+				{ExternalKey: "k2", Name: "b", Priority: intPtr(2)},
+			},
+			rowErrs: []bool{true, false},
+		},
+		{
+			name:   "ndjson type error is a row error",
+			format: importFormatNDJSON,
+			input:  "{\"externalKey\":\"k1\",\"name\":5}\n{\"externalKey\":\"k2\",\"name\":\"b\",\"priority\":4}\n",
+			wantRows: []goodsImportRow{
+				{},
+				{ExternalKey: "k2", Name: "b", Priority: intPtr(4)},
+			},
+			rowErrs: []bool{true, false},
+		},
+		{
+			name:   "ndjson syntax error breaks the file",
+			format: importFormatNDJSON,
+			input:  "{\"externalKey\":\"k1\",\"name\":\"a\"}\n{\"externalKey\":\n",
+			wantRows: []goodsImportRow{
+				{ExternalKey: "k1", Name: "a"},
+			},
+			rowErrs:   []bool{false},
+			wantBreak: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := newGoodsImportReader(test.format, strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("error creating reader [%s]", err.Error())
+			}
+
+			rows, rowErrs, err := readAllRows(reader)
+			if (err != nil) != test.wantBreak {
+				t.Fatalf("file error [%v], want break=[%t]", err, test.wantBreak)
+			}
+
+			if !reflect.DeepEqual(rows, test.wantRows) {
+				t.Errorf("rows [%+v], want [%+v]", rows, test.wantRows)
+			}
+
+			for i, rowErr := range rowErrs {
+				if (rowErr != nil) != test.rowErrs[i] {
+					t.Errorf("row [%d] error [%v], want error=[%t]", i+1, rowErr, test.rowErrs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewGoodsImportReaderErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{importFormatCSV, ""},
+		{importFormatCSV, "name,description\nTea,green\n"},
+		{importFormatCSV, "externalKey,description\nk1,green\n"},
+		{"xml", "<goods/>"},
+	}
+
+	for _, test := range tests {
+		_, err := newGoodsImportReader(test.format, strings.NewReader(test.input))
+		if err == nil {
+			t.Errorf("format [%s] input [%q]: reader created without error", test.format, test.input)
+		}
+	}
+}
+
+func TestImportGoodsReport(t *testing.T) {
+	initValidator()
+
+	// "broken" имитирует ошибку базы, остальные ключи "уже есть", если начинаются с old
+	upserted := []string{}
+	originalUpsertGood := upsertGood
+	defer func() {
+		upsertGood = originalUpsertGood
+	}()
+	upsertGood = func(good *postgres.Good, description *string, priority *int) (bool, error) {
+		if *good.ExternalKey == "broken" {
+			return false, errors.New(`pq: duplicate key value violates unique constraint "secret_idx"`)
+		}
+
+		upserted = append(upserted, *good.ExternalKey)
+		return !strings.HasPrefix(*good.ExternalKey, "old"), nil
+	}
+
+	input := "externalKey,name,description,priority\n" +
+		"new1,Tea,,\n" +
+		"old1,Coffee,,2\n" +
+		",No key,,\n" +
+		"long," + strings.Repeat("x", 101) + ",,\n" +
+		"zero,Zero,,0\n" +
+		"broken,Broken,,\n" +
+		"bad\"quote,Q,,\n" +
+		"new2,Milk,,\n"
+
+	reader, err := newGoodsImportReader(importFormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("error creating reader [%s]", err.Error())
+	}
+
+	report, err := importGoods(1, reader)
+	if err != nil {
+		t.Fatalf("error importing goods [%s]", err.Error())
+	}
+
+	if report.Rows != 8 || report.Created != 2 || report.Updated != 1 || report.Failed != 5 {
+		t.Errorf("report rows [%d], created [%d], updated [%d], failed [%d], want 8, 2, 1, 5",
+			report.Rows, report.Created, report.Updated, report.Failed)
+	}
+
+	wantUpserted := []string{"new1", "old1", "new2"}
+	if !reflect.DeepEqual(upserted, wantUpserted) {
+		t.Errorf("upserted [%v], want [%v]", upserted, wantUpserted)
+	}
+
+	wantErrors := []struct {
+		row   int
+		error string
+	}{
+		{3, errWrongParams},
+		{4, errWrongParams},
+		{5, errWrongParams},
+		{6, errInternal},
+		{7, errImportBadRow},
+	}
+	if len(report.Errors) != len(wantErrors) {
+		t.Fatalf("errors [%+v], want [%d]", report.Errors, len(wantErrors))
+	}
+	for i, want := range wantErrors {
+		got := report.Errors[i]
+		if got.Row != want.row || got.Error != want.error {
+			t.Errorf("error [%d] is row [%d] [%s], want row [%d] [%s]", i, got.Row, got.Error, want.row, want.error)
+		}
+	}
+
+	// текст ошибки базы не уходит клиенту
+	if report.Errors[3].Details != "" {
+		t.Errorf("database error details leaked to report [%s]", report.Errors[3].Details)
+	}
+}
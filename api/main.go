@@ -1,6 +1,7 @@
 package main
 
 import (
+	"clickhousedb"
 	"natsq"
 	"net/http"
 	"os"
@@ -11,6 +12,16 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImportCommand(os.Args[2:])
+		return
+	}
+
 	postgresConnParams, err := postgres.GetConnectionParams()
 	if err != nil {
 		logrus.Errorf("Error getting Connection Params [%s]", err.Error())
@@ -29,10 +40,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	// redis - необязательная зависимость: без него списки читаются из postgres
 	err = redisdb.OpenConnection(redisConnParams)
 	if err != nil {
-		logrus.Errorf("Error Open Redis Connection [%s]", err.Error())
-		os.Exit(1)
+		logrus.Warnf("Redis is unavailable, starting without cache [%s]", err.Error())
 	}
 
 	natsConnParams, err := natsq.GetConnectionParams()
@@ -47,6 +58,24 @@ func main() {
 		os.Exit(1)
 	}
 
+	postgres.StartOutboxRelay()
+	postgres.StartPurgeWorker()
+
+	err = redisdb.StartInvalidator()
+	if err != nil {
+		logrus.Errorf("Error Start Redis Invalidator [%s]", err.Error())
+		os.Exit(1)
+	}
+
+	clickhouseConnParams, err := clickhousedb.GetConnectionParams()
+	if err != nil {
+		logrus.Errorf("Error getting Clickhouse Connection Params [%s]", err.Error())
+		os.Exit(1)
+	}
+
+	// clickhouse - необязательный журнал: без него api работает, подключение повторяется в фоне
+	clickhousedb.Start(clickhouseConnParams)
+
 	initValidator()
 
 	// server
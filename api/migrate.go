@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"postgres"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = "usage: main migrate up|down|status|to <version>"
+
+// runMigrateCommand обрабатывает подкоманду "main migrate ..."
+func runMigrateCommand(args []string) {
+	if len(args) == 0 {
+		logrus.Error(migrateUsage)
+		os.Exit(1)
+	}
+
+	postgresConnParams, err := postgres.GetConnectionParams()
+	if err != nil {
+		logrus.Errorf("Error getting Connection Params [%s]", err.Error())
+		os.Exit(1)
+	}
+
+	err = postgres.Connect(postgresConnParams)
+	if err != nil {
+		logrus.Errorf("Error Connect Postgres [%s]", err.Error())
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "up":
+		err = postgres.MigrateUp()
+	case "down":
+		err = postgres.MigrateDown()
+	case "to":
+		if len(args) < 2 {
+			logrus.Error(migrateUsage)
+			os.Exit(1)
+		}
+
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			logrus.Errorf("error convert version [%s] to int [%s]", args[1], convErr.Error())
+			os.Exit(1)
+		}
+
+		err = postgres.MigrateTo(version)
+	case "status":
+		err = printMigrationsStatus()
+	default:
+		logrus.Error(migrateUsage)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		logrus.Errorf("Error migrate [%s] [%s]", args[0], err.Error())
+		os.Exit(1)
+	}
+}
+
+func printMigrationsStatus() error {
+	statuses, err := postgres.MigrationsStatus()
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%04d  %-30s  %s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return nil
+}
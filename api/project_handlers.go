@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"postgres"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const errProjectNotFound = "errors.project.notFound"
+
+// PROJECT CREATE
+type projectCreateUpdateRequest struct {
+	Name string `validate:"required,max=100"`
+}
+
+type projectResponse struct {
+	Success   bool      `json:"success,omitempty"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func projectCreate(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling project create request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	req := projectCreateUpdateRequest{}
+	resp := projectResponse{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logrus.Errorf("error decode request body [%s]", err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	err = validateRequest(req)
+	if err != nil {
+		logrus.Errorf("error validate request [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	project, err := postgres.ProjectCreate(req.Name)
+	if err != nil {
+		logrus.Errorf("error creating project [%s]", err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp.New(project)
+	logrus.Infof("successfully created new project with id [%d]", project.ID)
+	writeResponse(w, resp, 200)
+}
+
+func (resp *projectResponse) New(project postgres.Project) {
+	resp.Success = true
+	resp.ID = project.ID
+	resp.Name = project.Name
+	resp.CreatedAt = project.CreatedAt
+}
+
+// PROJECT UPDATE
+func projectUpdate(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling project update request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	req := projectCreateUpdateRequest{}
+	resp := projectResponse{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logrus.Errorf("error decode request body [%s]", err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	err = validateRequest(req)
+	if err != nil {
+		logrus.Errorf("error validate request [%s]", err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	queryValues := r.URL.Query()
+	id, err := strconv.Atoi(queryValues.Get("id"))
+	if err != nil {
+		logrus.Errorf("error convert id [%s] to int [%s]", queryValues.Get("id"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	project := postgres.Project{
+		ID: id,
+	}
+	err = project.Update(req.Name)
+	if errors.Is(err, postgres.ErrNotFound) {
+		badResponse.Error = errProjectNotFound
+		writeResponse(w, badResponse, 404)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error updating project with id=[%d] [%s]", id, err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp.New(project)
+	logrus.Infof("successfully updated project with id [%d]", id)
+	writeResponse(w, resp, 200)
+}
+
+// PROJECT DELETE
+// Проект удаляется вместе со всеми своими товарами
+type projectDeleteResponse struct {
+	Success      bool  `json:"success"`
+	ID           int   `json:"id"`
+	RemovedGoods int64 `json:"removedGoods"`
+}
+
+func projectDelete(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling project delete request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+
+	queryValues := r.URL.Query()
+	id, err := strconv.Atoi(queryValues.Get("id"))
+	if err != nil {
+		logrus.Errorf("error convert id [%s] to int [%s]", queryValues.Get("id"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	project := postgres.Project{
+		ID: id,
+	}
+	removedGoods, err := project.Delete()
+	if errors.Is(err, postgres.ErrNotFound) {
+		badResponse.Error = errProjectNotFound
+		writeResponse(w, badResponse, 404)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error deleting project with id=[%d] [%s]", id, err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp := projectDeleteResponse{
+		Success:      true,
+		ID:           id,
+		RemovedGoods: removedGoods,
+	}
+	logrus.Infof("successfully deleted project with id [%d] and [%d] goods", id, removedGoods)
+	writeResponse(w, resp, 200)
+}
+
+// PROJECT GET
+func projectGet(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling project get request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	resp := projectResponse{}
+
+	queryValues := r.URL.Query()
+	id, err := strconv.Atoi(queryValues.Get("id"))
+	if err != nil {
+		logrus.Errorf("error convert id [%s] to int [%s]", queryValues.Get("id"), err.Error())
+		badResponse.Error = errWrongParams
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	project := postgres.Project{
+		ID: id,
+	}
+	err = project.Get()
+	if errors.Is(err, postgres.ErrNotFound) {
+		badResponse.Error = errProjectNotFound
+		writeResponse(w, badResponse, 404)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error getting project with id=[%d] [%s]", id, err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp.New(project)
+	logrus.Infof("successfully got project with id [%d]", id)
+	writeResponse(w, resp, 200)
+}
+
+// PROJECTS LIST
+type projectsListResponse struct {
+	Success  bool              `json:"success"`
+	Projects []projectResponse `json:"projects"`
+}
+
+func projectsList(w http.ResponseWriter, r *http.Request) {
+	logrus.Infof("handling projects list request...")
+	badResponse := badResponse{
+		Success: false,
+		Error:   errInternal,
+	}
+	resp := projectsListResponse{}
+
+	projects := postgres.ProjectSlice{}
+	err := projects.Many()
+	if err != nil {
+		logrus.Errorf("error getting all projects [%s]", err.Error())
+		writeResponse(w, badResponse, 500)
+		return
+	}
+
+	resp.New(projects)
+	logrus.Info("successfully got all projects")
+	writeResponse(w, resp, 200)
+}
+
+func (resp *projectsListResponse) New(projectSlice postgres.ProjectSlice) {
+	resp.Success = true
+
+	projects := []projectResponse{}
+	for _, project := range projectSlice {
+		projects = append(projects, projectResponse{
+			ID:        project.ID,
+			Name:      project.Name,
+			CreatedAt: project.CreatedAt,
+		})
+	}
+
+	resp.Projects = projects
+}
@@ -18,11 +18,26 @@ type Routes []Route
 
 var routes = Routes{
 	Route{Name: "Ping", Method: "GET", Pattern: "/api/ping", HandlerFunc: pingHandler, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "RedisMetrics", Method: "GET", Pattern: "/api/metrics/redis", HandlerFunc: redisMetrics, MiddlewareAuthFunc: emptyMiddleWare},
 	Route{Name: "GoodCreate", Method: "POST", Pattern: "/api/good/create", HandlerFunc: goodCreate, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodGet", Method: "GET", Pattern: "/api/good/get", HandlerFunc: goodGet, MiddlewareAuthFunc: emptyMiddleWare},
 	Route{Name: "GoodUpdate", Method: "PATCH", Pattern: "/api/good/update", HandlerFunc: goodUpdate, MiddlewareAuthFunc: emptyMiddleWare},
 	Route{Name: "GoodDelete", Method: "DELETE", Pattern: "/api/good/delete", HandlerFunc: goodDelete, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodRestore", Method: "PATCH", Pattern: "/api/good/restore", HandlerFunc: goodRestore, MiddlewareAuthFunc: emptyMiddleWare},
 	Route{Name: "GoodsList", Method: "GET", Pattern: "/api/goods/list", HandlerFunc: goodsList, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodsSearch", Method: "GET", Pattern: "/api/goods/search", HandlerFunc: goodsSearch, MiddlewareAuthFunc: emptyMiddleWare},
 	Route{Name: "GoodReprioritize", Method: "PATCH", Pattern: "/api/good/reprioritize", HandlerFunc: goodReprioritize, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodsBulkCreate", Method: "POST", Pattern: "/api/goods/bulk-create", HandlerFunc: goodsBulkCreate, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodsBulkDelete", Method: "DELETE", Pattern: "/api/goods/bulk-delete", HandlerFunc: goodsBulkDelete, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodsBulkRestore", Method: "PATCH", Pattern: "/api/goods/bulk-restore", HandlerFunc: goodsBulkRestore, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodsImport", Method: "POST", Pattern: "/api/goods/import", HandlerFunc: goodsImport, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodsExport", Method: "GET", Pattern: "/api/goods/export", HandlerFunc: goodsExport, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "GoodsPurge", Method: "POST", Pattern: "/api/admin/goods/purge", HandlerFunc: goodsPurge, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "ProjectCreate", Method: "POST", Pattern: "/api/project/create", HandlerFunc: projectCreate, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "ProjectUpdate", Method: "PATCH", Pattern: "/api/project/update", HandlerFunc: projectUpdate, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "ProjectDelete", Method: "DELETE", Pattern: "/api/project/delete", HandlerFunc: projectDelete, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "ProjectGet", Method: "GET", Pattern: "/api/project/get", HandlerFunc: projectGet, MiddlewareAuthFunc: emptyMiddleWare},
+	Route{Name: "ProjectsList", Method: "GET", Pattern: "/api/projects/list", HandlerFunc: projectsList, MiddlewareAuthFunc: emptyMiddleWare},
 }
 
 func NewRouter() *mux.Router {
@@ -0,0 +1,204 @@
+package clickhousedb
+
+import (
+	"common"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/sirupsen/logrus"
+)
+
+var ClickhouseConn driver.Conn
+
+const reconnectInterval = 30 * time.Second
+
+type connectionParams struct {
+	Addr     string
+	User     string
+	Password string
+	DBName   string
+}
+
+// Start подключается к clickhouse и запускает consumer журнала. Clickhouse - необязательный журнал:
+// если он недоступен, api работает без него, а подключение повторяется в фоне
+func Start(connParams connectionParams) {
+	err := start(connParams)
+	if err == nil {
+		return
+	}
+
+	logrus.Warnf("Clickhouse is unavailable, retrying every [%s] [%s]", reconnectInterval, err.Error())
+	go func() {
+		ticker := time.NewTicker(reconnectInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			err := start(connParams)
+			if err == nil {
+				return
+			}
+			logrus.Warnf("Clickhouse is still unavailable [%s]", err.Error())
+		}
+	}()
+}
+
+func start(connParams connectionParams) error {
+	err := OpenConnection(connParams)
+	if err == nil {
+		err = StartConsumer()
+	}
+
+	if err != nil && ClickhouseConn != nil {
+		ClickhouseConn.Close()
+		ClickhouseConn = nil
+	}
+
+	return err
+}
+
+func OpenConnection(connParams connectionParams) (err error) {
+	logrus.Info("opening clickhouse connection...")
+
+	ClickhouseConn, err = clickhouse.Open(&clickhouse.Options{
+		Addr: []string{connParams.Addr},
+		Auth: clickhouse.Auth{
+			Database: connParams.DBName,
+			Username: connParams.User,
+			Password: connParams.Password,
+		},
+	})
+	if err != nil {
+		logrus.Errorf("error opening clickhouse connection [%s]", err.Error())
+		return err
+	}
+
+	err = ClickhouseConn.Ping(context.Background())
+	if err != nil {
+		logrus.Errorf("error pinging clickhouse connection [%s]", err.Error())
+		return err
+	}
+	logrus.Info("successfully opened clickhouse connection")
+
+	err = migrate()
+	if err != nil {
+		logrus.Errorf("error migrating clickhouse tables [%s]", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// goods_log пишется с доставкой at-least-once, поэтому повторы одного события схлопываются
+// ReplacingMergeTree по EventId. Схлопывание происходит при слиянии частей, точный журнал читается с FINAL
+const goodsLogColumns = `
+	Id          Int32,
+	ProjectId   Int32,
+	Name        String,
+	Description String,
+	Priority    Int32,
+	Removed     UInt8,
+	Operation   LowCardinality(String),
+	EventTime   DateTime,
+	EventId     String,
+	INDEX idx_id Id TYPE minmax GRANULARITY 1,
+	INDEX idx_project_id ProjectId TYPE minmax GRANULARITY 1,
+	INDEX idx_name Name TYPE bloom_filter GRANULARITY 1`
+
+const goodsLogEngine = `ENGINE = ReplacingMergeTree
+	ORDER BY (ProjectId, Id, EventTime, EventId)`
+
+func migrate() error {
+	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS goods_log (%s\n) %s", goodsLogColumns, goodsLogEngine)
+	err := ClickhouseConn.Exec(context.Background(), sql)
+	if err != nil {
+		logrus.Errorf("error creating goods_log table [%s]", err.Error())
+		return err
+	}
+
+	// для таблиц, созданных до появления id события
+	err = ClickhouseConn.Exec(context.Background(), "ALTER TABLE goods_log ADD COLUMN IF NOT EXISTS EventId String")
+	if err != nil {
+		logrus.Errorf("error adding EventId to goods_log [%s]", err.Error())
+		return err
+	}
+
+	return migrateGoodsLogEngine()
+}
+
+// migrateGoodsLogEngine переносит журнал из таблицы на MergeTree, созданной до дедупликации событий,
+// в таблицу на ReplacingMergeTree. Движок и ключ сортировки у существующей таблицы не меняются через ALTER
+func migrateGoodsLogEngine() error {
+	var engine string
+	sql := "SELECT engine FROM system.tables WHERE database = currentDatabase() AND name = 'goods_log'"
+	err := ClickhouseConn.QueryRow(context.Background(), sql).Scan(&engine)
+	if err != nil {
+		logrus.Errorf("error getting goods_log engine [%s]", err.Error())
+		return err
+	}
+
+	if engine != "MergeTree" {
+		return nil
+	}
+
+	logrus.Info("migrating goods_log to ReplacingMergeTree...")
+	columns := "Id, ProjectId, Name, Description, Priority, Removed, Operation, EventTime, EventId"
+	statements := []string{
+		"DROP TABLE IF EXISTS goods_log_replacing",
+		fmt.Sprintf("CREATE TABLE goods_log_replacing (%s\n) %s", goodsLogColumns, goodsLogEngine),
+		fmt.Sprintf("INSERT INTO goods_log_replacing (%s) SELECT %s FROM goods_log", columns, columns),
+		"EXCHANGE TABLES goods_log AND goods_log_replacing",
+		"DROP TABLE goods_log_replacing",
+	}
+	for _, statement := range statements {
+		err = ClickhouseConn.Exec(context.Background(), statement)
+		if err != nil {
+			logrus.Errorf("error migrating goods_log [%s] [%s]", statement, err.Error())
+			return err
+		}
+	}
+
+	logrus.Info("successfully migrated goods_log to ReplacingMergeTree")
+	return nil
+}
+
+func GetConnectionParams() (connectionParams, error) {
+	host, err := common.GetEnvVar("CLICKHOUSE_HOST")
+	if err != nil {
+		logrus.Errorf("error getting CLICKHOUSE_HOST var from env [%s]", err.Error())
+		return connectionParams{}, err
+	}
+
+	port, err := common.GetEnvVar("CLICKHOUSE_PORT")
+	if err != nil {
+		logrus.Errorf("error getting CLICKHOUSE_PORT var from env [%s]", err.Error())
+		return connectionParams{}, err
+	}
+
+	user, err := common.GetEnvVar("CLICKHOUSE_USER")
+	if err != nil {
+		logrus.Errorf("error getting CLICKHOUSE_USER var from env [%s]", err.Error())
+		return connectionParams{}, err
+	}
+
+	password, err := common.GetEnvVar("CLICKHOUSE_PASSWORD")
+	if err != nil {
+		logrus.Errorf("error getting CLICKHOUSE_PASSWORD var from env [%s]", err.Error())
+		return connectionParams{}, err
+	}
+
+	dbName, err := common.GetEnvVar("CLICKHOUSE_DB")
+	if err != nil {
+		logrus.Errorf("error getting CLICKHOUSE_DB var from env [%s]", err.Error())
+		return connectionParams{}, err
+	}
+
+	return connectionParams{
+		Addr:     fmt.Sprintf("%s:%s", host, port),
+		User:     user,
+		Password: password,
+		DBName:   dbName,
+	}, nil
+}
@@ -0,0 +1,188 @@
+package clickhousedb
+
+import (
+	"context"
+	"errors"
+	"event"
+	"natsq"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// durable consumer: после перезапуска продолжает с последнего подтвержденного события
+	durableName = "clickhouse-goods-log"
+
+	batchSize        = 100
+	maxPendingEvents = 100 * batchSize
+	flushInterval    = 5 * time.Second
+
+	// пауза перед повторной доставкой, чтобы недоступный clickhouse не крутил события по кругу
+	redeliveryDelay = 30 * time.Second
+
+	// предел на вставку пачки, чтобы зависший clickhouse не держал события дольше ackWait
+	insertTimeout = 10 * time.Second
+
+	// событие ждет в канале вставки предыдущей пачки, до flushInterval в своей пачке и еще одну вставку.
+	// Если не успеть подтвердить за ackWait, JetStream доставит его повторно
+	ackWait = flushInterval + 2*insertTimeout + 10*time.Second
+)
+
+type receivedEvent struct {
+	event event.GoodEvent
+	msg   *nats.Msg
+}
+
+var events = make(chan receivedEvent, batchSize)
+
+// StartConsumer читает "log-events" из JetStream и пишет события пачками в goods_log.
+// Пачка отправляется при наборе batchSize событий или раз в flushInterval,
+// сообщения подтверждаются только после успешной вставки.
+func StartConsumer() error {
+	err := provisionConsumer()
+	if err != nil {
+		return err
+	}
+
+	// queue group, чтобы при нескольких репликах api каждое событие писалось один раз
+	_, err = natsq.JetStream.QueueSubscribe(
+		natsq.LogEventsSubject,
+		durableName,
+		handleMessage,
+		nats.BindStream(natsq.GoodsStreamName),
+		nats.Durable(durableName),
+		nats.DeliverAll(),
+		nats.ManualAck(),
+		nats.AckWait(ackWait),
+		nats.MaxAckPending(maxPendingEvents),
+	)
+	if err != nil {
+		logrus.Errorf("error subscribing to [%s] [%s]", natsq.LogEventsSubject, err.Error())
+		return err
+	}
+
+	go batchLoop()
+
+	return nil
+}
+
+// provisionConsumer обновляет ackWait у durable consumer, созданного раньше с другим значением:
+// с отличающимся конфигом подписка на существующий consumer не создается
+func provisionConsumer() error {
+	info, err := natsq.JetStream.ConsumerInfo(natsq.GoodsStreamName, durableName)
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		// consumer создаст подписка
+		return nil
+	}
+	if err != nil {
+		logrus.Errorf("error getting consumer [%s] info [%s]", durableName, err.Error())
+		return err
+	}
+
+	if info.Config.AckWait == ackWait {
+		return nil
+	}
+
+	config := info.Config
+	config.AckWait = ackWait
+	_, err = natsq.JetStream.UpdateConsumer(natsq.GoodsStreamName, &config)
+	if err != nil {
+		logrus.Errorf("error updating consumer [%s] [%s]", durableName, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func handleMessage(msg *nats.Msg) {
+	goodEvent, err := event.Decode(msg.Data)
+	if err != nil {
+		logrus.Errorf("error decoding good event [%s] of version [%s] [%s]", msg.Data, msg.Header.Get(event.HeaderVersion), err.Error())
+		// битое сообщение или неизвестная версия не исправятся при повторной доставке
+		msg.Term()
+		return
+	}
+
+	events <- receivedEvent{event: goodEvent, msg: msg}
+}
+
+func batchLoop() {
+	batch := make([]receivedEvent, 0, batchSize)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case received := <-events:
+			batch = append(batch, received)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		err := insertBatch(batch)
+		if err != nil {
+			logrus.Errorf("error inserting [%d] goods log events to clickhouse [%s]", len(batch), err.Error())
+			// JetStream доставит события повторно через redeliveryDelay
+			for _, received := range batch {
+				received.msg.NakWithDelay(redeliveryDelay)
+			}
+			batch = batch[:0]
+			continue
+		}
+
+		for _, received := range batch {
+			err = received.msg.Ack()
+			if err != nil {
+				logrus.Errorf("error acking good [%d] event [%s]", received.event.GoodID, err.Error())
+			}
+		}
+
+		logrus.Infof("successfully inserted [%d] goods log events to clickhouse", len(batch))
+		batch = batch[:0]
+	}
+}
+
+func insertBatch(goodEvents []receivedEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), insertTimeout)
+	defer cancel()
+
+	batch, err := ClickhouseConn.PrepareBatch(ctx, "INSERT INTO goods_log")
+	if err != nil {
+		logrus.Errorf("error preparing clickhouse batch [%s]", err.Error())
+		return err
+	}
+
+	for _, received := range goodEvents {
+		for _, good := range received.event.Goods() {
+			var removed uint8
+			if good.Removed || received.event.Deleted() {
+				removed = 1
+			}
+
+			err = batch.Append(
+				int32(good.ID),
+				int32(good.ProjectID),
+				good.Name,
+				good.Description,
+				int32(good.Priority),
+				removed,
+				string(received.event.Type),
+				received.event.Timestamp,
+				received.event.ID,
+			)
+			if err != nil {
+				logrus.Errorf("error appending good [%d] event to clickhouse batch [%s]", good.ID, err.Error())
+				return err
+			}
+		}
+	}
+
+	return batch.Send()
+}
@@ -0,0 +1,39 @@
+module clickhousedb
+
+go 1.21
+
+require (
+	common v0.0.0-00010101000000-000000000000
+	github.com/ClickHouse/clickhouse-go/v2 v2.20.0
+	github.com/nats-io/nats.go v1.33.1
+	github.com/sirupsen/logrus v1.9.3
+	natsq v0.0.0-00010101000000-000000000000
+)
+
+require (
+	event v0.0.0-00010101000000-000000000000
+	github.com/ClickHouse/ch-go v0.61.3 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/go-faster/city v1.0.1 // indirect
+	github.com/go-faster/errors v0.7.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.7 // indirect
+	github.com/nats-io/nkeys v0.4.7 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/paulmach/orb v0.11.1 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/segmentio/asm v1.2.0 // indirect
+	github.com/shopspring/decimal v1.3.1 // indirect
+	go.opentelemetry.io/otel v1.24.0 // indirect
+	go.opentelemetry.io/otel/trace v1.24.0 // indirect
+	golang.org/x/crypto v0.18.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace common => ../common
+
+replace natsq => ../natsq
+
+replace event => ../event
@@ -18,3 +18,13 @@ func GetEnvVar(name string) (string, error) {
 
 	return val, nil
 }
+
+// GetEnvVarDefault возвращает значение переменной окружения или defaultValue, если ее нет
+func GetEnvVarDefault(name, defaultValue string) string {
+	val, ok := os.LookupEnv(name)
+	if !ok || val == "" {
+		return defaultValue
+	}
+
+	return val
+}
@@ -0,0 +1,176 @@
+package event
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SchemaVersion - текущая версия схемы GoodEvent. Увеличивается при несовместимых изменениях
+const SchemaVersion = 1
+
+// заголовки NATS, по которым подписчик узнает тип и версию события, не разбирая тело
+const (
+	HeaderType    = "Event-Type"
+	HeaderVersion = "Event-Version"
+)
+
+type Type string
+
+const (
+	TypeCreated       Type = "created"
+	TypeUpdated       Type = "updated"
+	TypeRemoved       Type = "removed"
+	TypeRestored      Type = "restored"
+	TypeReprioritized Type = "reprioritized"
+	TypePurged        Type = "purged"
+	TypeBatchCreated  Type = "batchCreated"
+)
+
+// Good - снимок товара до или после изменения
+type Good struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"projectId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Priority    int       `json:"priority"`
+	Removed     bool      `json:"removed"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// GoodEvent - событие об изменении товара.
+// Before пустой у created, After пустой, если товар удален из базы совсем.
+// У пакетных событий Before и After пустые, состояния товаров лежат в Batch, а GoodID = 0
+type GoodEvent struct {
+	ID        string    `json:"id"`
+	Type      Type      `json:"type"`
+	Version   int       `json:"version"`
+	ProjectID int       `json:"projectId"`
+	GoodID    int       `json:"goodId"`
+	Before    *Good     `json:"before,omitempty"`
+	After     *Good     `json:"after,omitempty"`
+	Batch     []Good    `json:"batch,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func NewGoodEvent(eventType Type, before, after *Good) (GoodEvent, error) {
+	id, err := newID()
+	if err != nil {
+		return GoodEvent{}, err
+	}
+
+	e := GoodEvent{
+		ID:        id,
+		Type:      eventType,
+		Version:   SchemaVersion,
+		Before:    before,
+		After:     after,
+		Timestamp: time.Now().UTC(),
+	}
+
+	snapshot := e.Snapshot()
+	if snapshot == nil {
+		return GoodEvent{}, fmt.Errorf("event [%s] has neither before nor after snapshot", eventType)
+	}
+	e.ProjectID = snapshot.ProjectID
+	e.GoodID = snapshot.ID
+
+	return e, nil
+}
+
+// NewGoodsBatchEvent - одно событие об изменении нескольких товаров одного проекта
+func NewGoodsBatchEvent(eventType Type, goods []Good) (GoodEvent, error) {
+	if len(goods) == 0 {
+		return GoodEvent{}, fmt.Errorf("batch event [%s] has no goods", eventType)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return GoodEvent{}, err
+	}
+
+	return GoodEvent{
+		ID:        id,
+		Type:      eventType,
+		Version:   SchemaVersion,
+		ProjectID: goods[0].ProjectID,
+		Batch:     goods,
+		Timestamp: time.Now().UTC(),
+	}, nil
+}
+
+// Goods возвращает последние известные состояния всех товаров события
+func (e GoodEvent) Goods() []Good {
+	if len(e.Batch) > 0 {
+		return e.Batch
+	}
+
+	snapshot := e.Snapshot()
+	if snapshot == nil {
+		return nil
+	}
+
+	return []Good{*snapshot}
+}
+
+// Deleted сообщает, что товар удален из базы совсем
+func (e GoodEvent) Deleted() bool {
+	return e.Before != nil && e.After == nil
+}
+
+// Snapshot возвращает последнее известное состояние товара
+func (e GoodEvent) Snapshot() *Good {
+	if e.After != nil {
+		return e.After
+	}
+
+	return e.Before
+}
+
+func (e GoodEvent) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (e GoodEvent) Headers() map[string]string {
+	return map[string]string{
+		HeaderType:    string(e.Type),
+		HeaderVersion: strconv.Itoa(e.Version),
+	}
+}
+
+// Decode разбирает событие и проверяет, что его версия поддерживается
+func Decode(data []byte) (GoodEvent, error) {
+	e := GoodEvent{}
+	err := json.Unmarshal(data, &e)
+	if err != nil {
+		return GoodEvent{}, err
+	}
+
+	if e.Version < 1 || e.Version > SchemaVersion {
+		return GoodEvent{}, fmt.Errorf("unsupported event version [%d]", e.Version)
+	}
+
+	if len(e.Goods()) == 0 {
+		return GoodEvent{}, fmt.Errorf("event [%s] has neither before nor after snapshot", e.ID)
+	}
+
+	return e, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	// uuid v4
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32]), nil
+}
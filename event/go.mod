@@ -0,0 +1,3 @@
+module event
+
+go 1.21
@@ -2,15 +2,31 @@ package natsq
 
 import (
 	"common"
+	"errors"
+	"strconv"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/sirupsen/logrus"
 )
 
-var NatsConn *nats.Conn
+const (
+	GoodsStreamName  = "GOODS_EVENTS"
+	LogEventsSubject = "log-events"
+
+	// окно, в котором JetStream отбрасывает сообщения с повторным Nats-Msg-Id
+	duplicatesWindow = 2 * time.Minute
+)
+
+var (
+	NatsConn  *nats.Conn
+	JetStream nats.JetStreamContext
+)
 
 type connectionParams struct {
-	Url string
+	Url            string
+	StreamMaxAge   time.Duration
+	StreamMaxBytes int64
 }
 
 func OpenConnection(connParams connectionParams) (err error) {
@@ -20,9 +36,72 @@ func OpenConnection(connParams connectionParams) (err error) {
 		return err
 	}
 
+	JetStream, err = NatsConn.JetStream()
+	if err != nil {
+		logrus.Errorf("error getting jetstream context [%s]", err.Error())
+		return err
+	}
+
+	err = provisionGoodsStream(connParams)
+	if err != nil {
+		logrus.Errorf("error provisioning goods stream [%s]", err.Error())
+		return err
+	}
+
 	return nil
 }
 
+// provisionGoodsStream создает или обновляет stream, в котором хранятся события товаров,
+// чтобы подписчики могли дочитать их после простоя или перечитать с любого места
+func provisionGoodsStream(connParams connectionParams) error {
+	config := &nats.StreamConfig{
+		Name:       GoodsStreamName,
+		Subjects:   []string{LogEventsSubject},
+		Retention:  nats.LimitsPolicy,
+		Storage:    nats.FileStorage,
+		MaxAge:     connParams.StreamMaxAge,
+		MaxBytes:   connParams.StreamMaxBytes,
+		Duplicates: duplicatesWindow,
+	}
+
+	_, err := JetStream.StreamInfo(GoodsStreamName)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = JetStream.AddStream(config)
+		if err != nil {
+			logrus.Errorf("error adding stream [%s] [%s]", GoodsStreamName, err.Error())
+			return err
+		}
+
+		logrus.Infof("successfully created stream [%s]", GoodsStreamName)
+		return nil
+	}
+	if err != nil {
+		logrus.Errorf("error getting stream [%s] info [%s]", GoodsStreamName, err.Error())
+		return err
+	}
+
+	_, err = JetStream.UpdateStream(config)
+	if err != nil {
+		logrus.Errorf("error updating stream [%s] [%s]", GoodsStreamName, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Publish публикует сообщение в JetStream и ждет подтверждения.
+// msgID используется для дедупликации повторных отправок
+func Publish(subject string, data []byte, msgID string, headers map[string]string) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	for key, value := range headers {
+		msg.Header.Set(key, value)
+	}
+
+	_, err := JetStream.PublishMsg(msg, nats.MsgId(msgID))
+	return err
+}
+
 func GetConnectionParams() (connectionParams, error) {
 	url, err := common.GetEnvVar("NATS_URL")
 	if err != nil {
@@ -30,7 +109,23 @@ func GetConnectionParams() (connectionParams, error) {
 		return connectionParams{}, err
 	}
 
+	maxAgeParam := common.GetEnvVarDefault("NATS_STREAM_MAX_AGE", "168h")
+	maxAge, err := time.ParseDuration(maxAgeParam)
+	if err != nil {
+		logrus.Errorf("error parsing NATS_STREAM_MAX_AGE [%s] [%s]", maxAgeParam, err.Error())
+		return connectionParams{}, err
+	}
+
+	maxBytesParam := common.GetEnvVarDefault("NATS_STREAM_MAX_BYTES", "-1")
+	maxBytes, err := strconv.ParseInt(maxBytesParam, 10, 64)
+	if err != nil {
+		logrus.Errorf("error convert NATS_STREAM_MAX_BYTES [%s] to int [%s]", maxBytesParam, err.Error())
+		return connectionParams{}, err
+	}
+
 	return connectionParams{
-		Url: url,
+		Url:            url,
+		StreamMaxAge:   maxAge,
+		StreamMaxBytes: maxBytes,
 	}, nil
 }
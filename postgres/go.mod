@@ -16,6 +16,7 @@ require (
 )
 
 require (
+	event v0.0.0-00010101000000-000000000000
 	github.com/go-redis/redis v6.15.9+incompatible // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
@@ -38,3 +39,5 @@ replace common => ../common
 replace redisdb => ../redisdb
 
 replace natsq => ../natsq
+
+replace event => ../event
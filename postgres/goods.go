@@ -1,38 +1,307 @@
 package postgres
 
 import (
+	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"event"
 	"redisdb"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var errHookNoRows = "sql: Scan error on column index 0, name \"max\": converting NULL to int is unsupported"
+var (
+	ErrVersionConflict = errors.New("good version conflict")
+	ErrNoExternalKey   = errors.New("good has no external key")
+)
+
+// пространство ключей advisory lock для выдачи приоритетов, второй ключ - id проекта
+const goodsPriorityLockKey = 1
 
 func (m *Good) Create() error {
-	return postgresDB.Create(&m).Error
+	err := postgresDB.Transaction(func(tx *gorm.DB) error {
+		return createGood(tx, m)
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateGoodsCache(m.ProjectID)
+	return nil
+}
+
+// Create добавляет товары в проект одной вставкой. Товары получают приоритеты подряд
+// после последнего товара проекта, об их создании пишется одно пакетное событие
+func (m *GoodSlice) Create(projectID int) error {
+	if len(*m) == 0 {
+		return nil
+	}
+
+	err := postgresDB.Transaction(func(tx *gorm.DB) error {
+		priority, err := nextPriority(tx, projectID)
+		if err != nil {
+			return err
+		}
+
+		for i := range *m {
+			(*m)[i].ProjectID = projectID
+			(*m)[i].Priority = priority + i
+		}
+
+		// приоритеты уже выданы, BeforeCreate не нужен
+		err = tx.Session(&gorm.Session{SkipHooks: true}).Create(m).Error
+		if err != nil {
+			logrus.Errorf("error creating [%d] goods in projectID=[%d] [%s]", len(*m), projectID, err.Error())
+			return err
+		}
+
+		ids := make([]int, 0, len(*m))
+		for _, good := range *m {
+			ids = append(ids, good.ID)
+		}
+
+		err = updateSearchVector(tx, ids...)
+		if err != nil {
+			return err
+		}
+
+		return writeBatchOutbox(tx, event.TypeBatchCreated, *m)
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateGoodsCache(projectID)
+	return nil
 }
 
-func (m *Good) Update(name, description string) error {
-	err := postgresDB.Where(&m).First(&m).Error
+// Get находит товар по id и projectID
+func (m *Good) Get() error {
+	key := redisdb.GoodKey(m.ProjectID, m.ID)
+	if getCached(key, m) {
+		logrus.Info("good from redis")
+		return nil
+	}
+
+	logrus.Info("good from postgres")
+	err := postgresDB.Where("id = ? AND project_id = ?", m.ID, m.ProjectID).Take(m).Error
 	if err != nil {
 		logrus.Errorf("error finding good by id=[%d], projectID=[%d] [%s]", m.ID, m.ProjectID, err.Error())
 		return err
 	}
 
-	m.Name = name
-	m.Description = description
+	cacheGoods(m.ProjectID, key, m)
+	return nil
+}
+
+// Update меняет только переданные (не nil) название и описание товара. Если expectedVersion задана
+// и не совпадает с текущей версией, возвращает ErrVersionConflict, а в m остается текущее состояние товара
+func (m *Good) Update(name, description *string, expectedVersion *int) error {
+	changed := false
+	err := postgresDB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&m).First(&m).Error
+		if err != nil {
+			logrus.Errorf("error finding good by id=[%d], projectID=[%d] [%s]", m.ID, m.ProjectID, err.Error())
+			return err
+		}
+
+		if expectedVersion != nil && *expectedVersion != m.Version {
+			logrus.Errorf("good with id=[%d], projectID=[%d] has version [%d], expected [%d]", m.ID, m.ProjectID, m.Version, *expectedVersion)
+			return ErrVersionConflict
+		}
+
+		updates := map[string]interface{}{}
+		if name != nil && *name != m.Name {
+			updates["name"] = *name
+		}
+		if description != nil && *description != m.Description {
+			updates["description"] = *description
+		}
+
+		// нечего менять: не пишем в базу и не поднимаем версию
+		if len(updates) == 0 {
+			return nil
+		}
 
-	return postgresDB.Save(&m).Error
+		changed = true
+		return updateGood(tx, m, updates)
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		invalidateGoodsCache(m.ProjectID)
+	}
+	return nil
 }
 
+// Upsert создает товар проекта или обновляет название и описание товара с тем же ExternalKey.
+// description nil оставляет описание существующего товара как есть, новому товару - пустое.
+// priority задает место товара в списке: без него новый товар встает в конец, а у существующего
+// приоритет не меняется. Возвращает true, если товар создан
+func (m *Good) Upsert(description *string, priority *int) (bool, error) {
+	if m.ExternalKey == nil {
+		return false, ErrNoExternalKey
+	}
+
+	created, changed := false, false
+	err := postgresDB.Transaction(func(tx *gorm.DB) error {
+		// под этим же lock'ом выдаются и сдвигаются приоритеты
+		err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", goodsPriorityLockKey, m.ProjectID).Error
+		if err != nil {
+			logrus.Errorf("error locking priorities of projectID=[%d] [%s]", m.ProjectID, err.Error())
+			return err
+		}
+
+		existing := Good{}
+		err = tx.Where("project_id = ? AND external_key = ?", m.ProjectID, *m.ExternalKey).Take(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if description != nil {
+				m.Description = *description
+			}
+
+			err = createGood(tx, m)
+			if err != nil {
+				logrus.Errorf("error creating good with externalKey=[%s], projectID=[%d] [%s]", *m.ExternalKey, m.ProjectID, err.Error())
+				return err
+			}
+
+			created, changed = true, true
+		case err != nil:
+			logrus.Errorf("error finding good by externalKey=[%s], projectID=[%d] [%s]", *m.ExternalKey, m.ProjectID, err.Error())
+			return err
+		default:
+			updates := map[string]interface{}{}
+			if existing.Name != m.Name {
+				updates["name"] = m.Name
+			}
+			if description != nil && *description != existing.Description {
+				updates["description"] = *description
+			}
+
+			if len(updates) > 0 {
+				err = updateGood(tx, &existing, updates)
+				if err != nil {
+					logrus.Errorf("error updating good with id=[%d], projectID=[%d] [%s]", existing.ID, m.ProjectID, err.Error())
+					return err
+				}
+
+				changed = true
+			}
+
+			*m = existing
+		}
+
+		if priority == nil || *priority == m.Priority {
+			return nil
+		}
+
+		err = shiftPriorities(tx, *priority, *m)
+		if err != nil {
+			return err
+		}
+
+		changed = true
+		return tx.First(m, m.ID).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if changed {
+		invalidateGoodsCache(m.ProjectID)
+	}
+	return created, nil
+}
+
+// createGood вставляет товар, строит его поисковый вектор и пишет событие created в транзакции tx
+func createGood(tx *gorm.DB, m *Good) error {
+	err := tx.Create(m).Error
+	if err != nil {
+		return err
+	}
+
+	err = updateSearchVector(tx, m.ID)
+	if err != nil {
+		return err
+	}
+
+	return writeOutbox(tx, event.TypeCreated, nil, m)
+}
+
+// updateGood записывает updates в товар m, перечитывает его с новой версией и пишет событие updated
+// в транзакции tx. m должен быть прочитан в этой же транзакции
+func updateGood(tx *gorm.DB, m *Good, updates map[string]interface{}) error {
+	// gorm записывает новые значения обратно в m, поэтому снимок берется до Updates
+	before := *m
+	err := tx.Model(m).Updates(updates).Error
+	if err != nil {
+		return err
+	}
+
+	err = updateSearchVector(tx, m.ID)
+	if err != nil {
+		return err
+	}
+
+	// версию поднял триггер
+	err = tx.First(m, m.ID).Error
+	if err != nil {
+		return err
+	}
+
+	return writeOutbox(tx, event.TypeUpdated, &before, m)
+}
+
+// Delete помечает товар удаленным
 func (m *Good) Delete() error {
+	return m.setRemoved(true, event.TypeRemoved)
+}
+
+// Restore снимает с товара пометку удаления
+func (m *Good) Restore() error {
+	return m.setRemoved(false, event.TypeRestored)
+}
+
+// setRemoved меняет пометку удаления через GoodSlice.setRemoved, чтобы одиночное и пакетное
+// удаление шли одной транзакцией. Если товара нет, возвращает ErrNotFound
+func (m *Good) setRemoved(removed bool, eventType event.Type) error {
+	goods := GoodSlice{}
+	_, err := goods.setRemoved(m.ProjectID, []int{m.ID}, removed, eventType)
+	if err != nil {
+		return err
+	}
+
+	if len(goods) == 0 {
+		logrus.Errorf("error finding good by id=[%d], projectID=[%d] [%s]", m.ID, m.ProjectID, ErrNotFound.Error())
+		return ErrNotFound
+	}
+
+	*m = goods[0]
+	return nil
+}
+
+// Delete помечает удаленными товары проекта с переданными id. В m попадают найденные товары,
+// возвращаются id тех, чье состояние изменилось
+func (m *GoodSlice) Delete(projectID int, ids []int) (map[int]bool, error) {
+	return m.setRemoved(projectID, ids, true, event.TypeRemoved)
+}
+
+// Restore снимает пометку удаления с товаров проекта с переданными id
+func (m *GoodSlice) Restore(projectID int, ids []int) (map[int]bool, error) {
+	return m.setRemoved(projectID, ids, false, event.TypeRestored)
+}
+
+func (m *GoodSlice) setRemoved(projectID int, ids []int, removed bool, eventType event.Type) (map[int]bool, error) {
 	tx := postgresDB.Begin()
 	if tx.Error != nil {
 		logrus.Errorf("error beginning transaction [%s]", tx.Error.Error())
-		return tx.Error
+		return nil, tx.Error
 	}
 
 	defer func() {
@@ -44,105 +313,255 @@ func (m *Good) Delete() error {
 	err := tx.Exec("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE").Error
 	if err != nil {
 		logrus.Errorf("error setting transaction level [%s]", err.Error())
-		return err
+		tx.Rollback()
+		return nil, err
 	}
 
-	err = tx.Where(&m).First(&m).Error
+	err = tx.Where("project_id = ? AND id IN ?", projectID, ids).Order("id").Find(m).Error
 	if err != nil {
-		logrus.Errorf("error finding good by id=[%d], projectID=[%d] [%s]", m.ID, m.ProjectID, err.Error())
+		logrus.Errorf("error finding goods %v of projectID=[%d] [%s]", ids, projectID, err.Error())
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 
-	m.Removed = true
+	changed := map[int]bool{}
+	befores := map[int]Good{}
+	for _, good := range *m {
+		if good.Removed != removed {
+			changed[good.ID] = true
+			befores[good.ID] = good
+		}
+	}
 
-	err = tx.Save(&m).Error
-	if err != nil {
-		logrus.Errorf("error deleting good with id=[%d], projectID=[%d] [%s]", m.ID, m.ProjectID, err.Error())
+	if len(changed) == 0 {
 		tx.Rollback()
-		return err
+		return changed, nil
 	}
 
-	return tx.Commit().Error
-}
+	changedIDs := make([]int, 0, len(changed))
+	for id := range changed {
+		changedIDs = append(changedIDs, id)
+	}
 
-func (m *GoodSlice) Many(limit, offset int) (int, error) {
-	allGoods := GoodSlice{}
+	var removedAt *time.Time
+	if removed {
+		now := time.Now()
+		removedAt = &now
+	}
 
-	redisAllGoods, err := redisdb.RedisClient.Get(redisdb.AllGoodsKey).Bytes()
+	err = tx.Model(&Good{}).Where("id IN ?", changedIDs).
+		Updates(map[string]interface{}{"removed": removed, "removed_at": removedAt}).Error
 	if err != nil {
-		// geting from postgres
-		logrus.Info("all goods from postgres")
+		logrus.Errorf("error setting removed=[%t] for goods %v of projectID=[%d] [%s]", removed, changedIDs, projectID, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
 
-		err = postgresDB.Find(&allGoods).Error
-		if err != nil && err != gorm.ErrRecordNotFound {
-			logrus.Errorf("error finding goods in db [%s]", err.Error())
-			return -1, err
-		}
+	// версии поднял триггер
+	err = tx.Where("project_id = ? AND id IN ?", projectID, ids).Order("id").Find(m).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
-		// caching to redis
-		err = redisdb.Cache(redisdb.AllGoodsKey, allGoods)
-		if err != nil {
-			logrus.Errorf("error caching goods in redis [%s]", err.Error())
-			return -1, err
+	for i := range *m {
+		if !changed[(*m)[i].ID] {
+			continue
 		}
-	} else {
-		logrus.Info("all goods from redis")
 
-		err := json.Unmarshal(redisAllGoods, &allGoods)
+		before := befores[(*m)[i].ID]
+		err = writeOutbox(tx, eventType, &before, &(*m)[i])
 		if err != nil {
-			logrus.Errorf("error unmarshal allGoods from redis [%s]", err.Error())
-			return -1, err
+			tx.Rollback()
+			return nil, err
 		}
 	}
 
-	redisLimitOffsetGoods, err := redisdb.RedisClient.Get(fmt.Sprintf("goods_%d_%d", limit, offset)).Bytes()
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	invalidateGoodsCache(projectID)
+	return changed, nil
+}
+
+func (m *GoodSlice) Many(projectID, limit, offset int, filter GoodsFilter) (GoodsCounts, error) {
+	counts, err := goodsCounts(projectID, filter)
 	if err != nil {
+		return GoodsCounts{}, err
+	}
+
+	key := redisdb.GoodsKey(projectID, limit, offset, filter.cacheKey())
+	if getCached(key, m) {
+		logrus.Info("limit offset goods from redis")
+	} else {
 		// geting from postgres
 		logrus.Info("limit offset goods from postgres")
 
-		err = postgresDB.Limit(limit).Offset(offset).Order("id").Find(&m).Error
+		query := filter.where(postgresDB.Where("project_id = ?", projectID))
+		err := filter.order(query).Limit(limit).Offset(offset).Find(&m).Error
 		if err != nil && err != gorm.ErrRecordNotFound {
 			logrus.Errorf("error finding goods with limit and offset in db [%s]", err.Error())
-			return -1, err
+			return GoodsCounts{}, err
 		}
 
 		// caching to redis
-		err = redisdb.Cache(fmt.Sprintf("goods_%d_%d", limit, offset), &m)
+		cacheGoods(projectID, key, &m)
+	}
+
+	return counts, nil
+}
+
+// GoodsExport - открытая выборка товаров проекта для выгрузки. Строки читаются из postgres
+// по мере обработки, вся выборка в памяти не держится и в redis не кешируется
+type GoodsExport struct {
+	projectID int
+	rows      *sql.Rows
+}
+
+// OpenGoodsExport выполняет запрос товаров проекта в порядке сортировки фильтра. Ошибка запроса
+// возвращается здесь, до того как вызывающий начнет писать ответ. Выборку нужно закрыть через Close
+func OpenGoodsExport(projectID int, filter GoodsFilter) (*GoodsExport, error) {
+	query := filter.where(postgresDB.Model(&Good{}).Where("project_id = ?", projectID))
+	rows, err := filter.order(query).Rows()
+	if err != nil {
+		logrus.Errorf("error querying goods of projectID=[%d] for export [%s]", projectID, err.Error())
+		return nil, err
+	}
+
+	return &GoodsExport{projectID: projectID, rows: rows}, nil
+}
+
+// Each передает в fn товары по одному. Ошибка fn останавливает чтение
+func (e *GoodsExport) Each(fn func(Good) error) error {
+	for e.rows.Next() {
+		good := Good{}
+		err := postgresDB.ScanRows(e.rows, &good)
 		if err != nil {
-			logrus.Errorf("error caching goods in redis [%s]", err.Error())
-			return -1, err
+			logrus.Errorf("error scanning exported good of projectID=[%d] [%s]", e.projectID, err.Error())
+			return err
 		}
-	} else {
-		logrus.Info("limit offset goods from redis")
-		// getting from redis
-		err := json.Unmarshal(redisLimitOffsetGoods, m)
+
+		err = fn(good)
 		if err != nil {
-			logrus.Errorf("error unmarshal limit offset goods from redis [%s]", err.Error())
-			return -1, err
+			return err
 		}
 	}
 
-	return len(allGoods), nil
+	return e.rows.Err()
 }
 
-func (m *GoodSlice) ManyByPriority(priority int) error {
-	return postgresDB.Where("priority >= ?", priority).Order("priority").Find(&m).Error
+func (e *GoodsExport) Close() error {
+	return e.rows.Close()
 }
 
-func (m *GoodSlice) Reprioritize(newPriority int, good *Good) error {
-	err := postgresDB.Where(&good).First(&good).Error
+// ManyByCursor возвращает страницу товаров после cursor (nil - с начала) и курсор следующей страницы.
+// Keyset-запрос не кешируется: он дешевый при любой глубине и не плодит ключи в redis
+func (m *GoodSlice) ManyByCursor(projectID, limit int, cursor *GoodsCursor, filter GoodsFilter) (GoodsCounts, *GoodsCursor, error) {
+	if cursor != nil && (cursor.SortBy != filter.SortBy || cursor.Desc != filter.Desc) {
+		return GoodsCounts{}, nil, ErrCursorMismatch
+	}
+
+	counts, err := goodsCounts(projectID, filter)
 	if err != nil {
-		logrus.Errorf("error finding good by id=[%d], projectID=[%d] [%s]", good.ID, good.ProjectID, err.Error())
-		return err
+		return GoodsCounts{}, nil, err
 	}
 
-	err = m.ManyByPriority(good.Priority)
+	query := filter.where(postgresDB.Where("project_id = ?", projectID))
+	if cursor != nil {
+		query = filter.after(query, *cursor)
+	}
+
+	// берем на одну запись больше, чтобы понять, есть ли следующая страница
+	err = filter.order(query).Limit(limit + 1).Find(&m).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logrus.Errorf("error finding goods by cursor in db [%s]", err.Error())
+		return GoodsCounts{}, nil, err
+	}
+
+	if len(*m) <= limit {
+		return counts, nil, nil
+	}
+
+	*m = (*m)[:limit]
+	next := filter.cursorAt((*m)[limit-1])
+	return counts, &next, nil
+}
+
+// GoodsCounts - счетчики товаров проекта
+type GoodsCounts struct {
+	Total   int `json:"total"`
+	Removed int `json:"removed"`
+}
+
+// goodsCounts считает товары проекта агрегатом в postgres и кеширует только сами счетчики
+func goodsCounts(projectID int, filter GoodsFilter) (GoodsCounts, error) {
+	counts := GoodsCounts{}
+
+	key := redisdb.GoodsCountsKey(projectID, filter.countsKey())
+	if getCached(key, &counts) {
+		logrus.Info("goods counts from redis")
+		return counts, nil
+	}
+
+	logrus.Info("goods counts from postgres")
+
+	// removed считается без фильтра по removed, иначе в списке без удаленных товаров он всегда 0.
+	// total - по всем фильтрам, это размер списка
+	countsFilter := filter
+	countsFilter.Removed = nil
+	query := countsFilter.where(postgresDB.Model(&Good{}).Where("project_id = ?", projectID))
+	if filter.Removed != nil {
+		query = query.Select("COUNT(*) FILTER (WHERE removed = ?) AS total, COUNT(*) FILTER (WHERE removed) AS removed", *filter.Removed)
+	} else {
+		query = query.Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE removed) AS removed")
+	}
+	err := query.Scan(&counts).Error
 	if err != nil {
-		logrus.Errorf("error getting goods by priority [%s]", err.Error())
-		return err
+		logrus.Errorf("error counting goods in db [%s]", err.Error())
+		return GoodsCounts{}, err
+	}
+
+	cacheGoods(projectID, key, counts)
+	return counts, nil
+}
+
+// getCached читает значение из redis в dest. Недоступный redis или битые данные считаются промахом
+func getCached(key string, dest interface{}) bool {
+	data, err := redisdb.Get(key)
+	if err != nil {
+		// при открытом breaker'е redis не опрашивался, о его недоступности уже сообщил breaker
+		if err != redisdb.ErrNil && err != redisdb.ErrUnavailable {
+			logrus.Errorf("error getting [%s] from redis, falling back to postgres [%s]", key, err.Error())
+		}
+		return false
+	}
+
+	err = json.Unmarshal(data, dest)
+	if err != nil {
+		logrus.Errorf("error unmarshal [%s] from redis [%s]", key, err.Error())
+		return false
 	}
 
+	return true
+}
+
+// cacheGoods кеширует данные проекта. Ошибка кеширования не должна ломать ответ клиенту
+func cacheGoods(projectID int, key string, data interface{}) {
+	err := redisdb.CacheGoods(projectID, key, data)
+	if err != nil && err != redisdb.ErrUnavailable {
+		logrus.Errorf("error caching [%s] in redis [%s]", key, err.Error())
+	}
+}
+
+func (m *GoodSlice) ManyByPriority(projectID, priority int) error {
+	return postgresDB.Where("project_id = ? AND priority >= ?", projectID, priority).Order("priority").Find(&m).Error
+}
+
+// Reprioritize ставит товару newPriority, а товары проекта с приоритетом >= newPriority сдвигает за него.
+// Приоритеты уникальны в пределах проекта.
+func (m *GoodSlice) Reprioritize(newPriority int, good *Good) error {
 	tx := postgresDB.Begin()
 	if tx.Error != nil {
 		logrus.Errorf("error beginning transaction [%s]", tx.Error.Error())
@@ -155,23 +574,27 @@ func (m *GoodSlice) Reprioritize(newPriority int, good *Good) error {
 		}
 	}()
 
-	err = tx.Exec("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE").Error
+	// уровень изоляции остается READ COMMITTED: запись приоритетов упорядочивает advisory lock,
+	// а снимок SERIALIZABLE взялся бы до получения lock'а и не увидел бы закоммиченные за время ожидания изменения
+	err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", goodsPriorityLockKey, good.ProjectID).Error
 	if err != nil {
-		logrus.Errorf("error setting transaction level [%s]", err.Error())
+		logrus.Errorf("error locking priorities of projectID=[%d] [%s]", good.ProjectID, err.Error())
+		tx.Rollback()
 		return err
 	}
 
-	nextPriority := newPriority
-	for _, elem := range *m {
-		elem.Priority = nextPriority
-		err := tx.Model(&elem).Updates(elem).Error
-		if err != nil {
-			logrus.Errorf("error saving good [%d] with new piority [%d] [%s]", elem.ID, elem.Priority, err.Error())
-			tx.Rollback()
-			return err
-		}
+	// текущий приоритет читается только под lock'ом, иначе сдвиг считается от устаревшего значения
+	err = tx.Where(&good).First(&good).Error
+	if err != nil {
+		logrus.Errorf("error finding good by id=[%d], projectID=[%d] [%s]", good.ID, good.ProjectID, err.Error())
+		tx.Rollback()
+		return err
+	}
 
-		nextPriority++
+	err = shiftPriorities(tx, newPriority, *good)
+	if err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	err = tx.Commit().Error
@@ -181,18 +604,80 @@ func (m *GoodSlice) Reprioritize(newPriority int, good *Good) error {
 		return err
 	}
 
-	return m.ManyByPriority(newPriority)
+	invalidateGoodsCache(good.ProjectID)
+	return m.ManyByPriority(good.ProjectID, newPriority)
+}
+
+// shiftPriorities ставит good приоритет newPriority и сдвигает за него товары проекта с приоритетом >= newPriority.
+// Вызывается под advisory lock приоритетов проекта
+func shiftPriorities(tx *gorm.DB, newPriority int, good Good) error {
+	following := GoodSlice{}
+	err := tx.Where("project_id = ? AND priority >= ? AND id <> ?", good.ProjectID, newPriority, good.ID).Order("priority").Find(&following).Error
+	if err != nil {
+		logrus.Errorf("error getting goods by priority [%s]", err.Error())
+		return err
+	}
+
+	priority := newPriority
+	for _, elem := range append(GoodSlice{good}, following...) {
+		if elem.Priority != priority {
+			before := elem
+			err := tx.Model(&elem).Update("priority", priority).Error
+			if err != nil {
+				logrus.Errorf("error saving good [%d] with new piority [%d] [%s]", elem.ID, priority, err.Error())
+				return err
+			}
+
+			elem.Priority = priority
+			err = writeOutbox(tx, event.TypeReprioritized, &before, &elem)
+			if err != nil {
+				return err
+			}
+		}
+
+		priority++
+	}
+
+	return nil
+}
+
+// invalidateGoodsCache сразу сбрасывает кеш проекта после записи, чтобы клиент видел свои изменения.
+// Остальные реплики дополнительно сбрасывают кеш по событию из NATS (redisdb.StartInvalidator).
+func invalidateGoodsCache(projectID int) {
+	err := redisdb.InvalidateGoods(projectID)
+	if err != nil {
+		logrus.Errorf("error invalidating goods cache of projectID=[%d] [%s]", projectID, err.Error())
+	}
 }
 
+// BeforeCreate выполняется внутри транзакции создания. Advisory lock по проекту держится до коммита,
+// поэтому параллельные создания в одном проекте получают уникальные приоритеты подряд.
 func (m *Good) BeforeCreate(tx *gorm.DB) error {
-	var maxPriority int
-	sql := "SELECT MAX(priority) FROM goods"
-	err := postgresDB.Raw(sql).Scan(&maxPriority).Error
-	if err != nil && err.Error() != errHookNoRows {
-		logrus.Errorf("error hook create good [%s]", err.Error())
+	priority, err := nextPriority(tx.Session(&gorm.Session{NewDB: true}), m.ProjectID)
+	if err != nil {
 		return err
 	}
 
-	m.Priority = maxPriority + 1
+	m.Priority = priority
 	return nil
 }
+
+// nextPriority берет advisory lock приоритетов проекта до конца транзакции tx
+// и возвращает приоритет, следующий за последним товаром проекта
+func nextPriority(tx *gorm.DB, projectID int) (int, error) {
+	err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", goodsPriorityLockKey, projectID).Error
+	if err != nil {
+		logrus.Errorf("error locking priorities of projectID=[%d] [%s]", projectID, err.Error())
+		return 0, err
+	}
+
+	var maxPriority int
+	sql := "SELECT COALESCE(MAX(priority), 0) FROM goods WHERE project_id = ?"
+	err = tx.Raw(sql, projectID).Scan(&maxPriority).Error
+	if err != nil {
+		logrus.Errorf("error getting max priority of projectID=[%d] [%s]", projectID, err.Error())
+		return 0, err
+	}
+
+	return maxPriority + 1, nil
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	GoodsSortID        = "id"
+	GoodsSortPriority  = "priority"
+	GoodsSortName      = "name"
+	GoodsSortCreatedAt = "createdAt"
+)
+
+var goodsSortColumns = map[string]string{
+	GoodsSortID:        "id",
+	GoodsSortPriority:  "priority",
+	GoodsSortName:      "name",
+	GoodsSortCreatedAt: "created_at",
+}
+
+var ErrCursorMismatch = errors.New("cursor was made for another sorting")
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GoodsFilter - фильтры и сортировка списка товаров проекта. Пустые поля не фильтруют
+type GoodsFilter struct {
+	Removed     *bool
+	Name        string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SortBy      string
+	Desc        bool
+}
+
+// GoodsCursor - позиция последнего товара страницы в списке, отсортированном по (SortBy, id)
+type GoodsCursor struct {
+	SortBy    string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	ID        int       `json:"i"`
+	Priority  int       `json:"p,omitempty"`
+	Name      string    `json:"n,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+}
+
+func (f GoodsFilter) where(query *gorm.DB) *gorm.DB {
+	if f.Removed != nil {
+		query = query.Where("removed = ?", *f.Removed)
+	}
+
+	if f.Name != "" {
+		query = query.Where("name ILIKE ?", "%"+likeEscaper.Replace(f.Name)+"%")
+	}
+
+	if f.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *f.CreatedFrom)
+	}
+
+	if f.CreatedTo != nil {
+		query = query.Where("created_at < ?", *f.CreatedTo)
+	}
+
+	return query
+}
+
+func (f GoodsFilter) order(query *gorm.DB) *gorm.DB {
+	column := f.sortColumn()
+	if f.Desc {
+		query = query.Order(column + " DESC")
+	} else {
+		query = query.Order(column)
+	}
+
+	if column == "id" {
+		return query
+	}
+
+	// id делает порядок однозначным при одинаковых значениях
+	if f.Desc {
+		return query.Order("id DESC")
+	}
+
+	return query.Order("id")
+}
+
+// after оставляет товары строго после cursor в порядке сортировки фильтра
+func (f GoodsFilter) after(query *gorm.DB, cursor GoodsCursor) *gorm.DB {
+	op := ">"
+	if f.Desc {
+		op = "<"
+	}
+
+	column := f.sortColumn()
+	switch column {
+	case "id":
+		return query.Where("id "+op+" ?", cursor.ID)
+	case "priority":
+		return query.Where("(priority, id) "+op+" (?, ?)", cursor.Priority, cursor.ID)
+	case "name":
+		return query.Where("(name, id) "+op+" (?, ?)", cursor.Name, cursor.ID)
+	default:
+		return query.Where("(created_at, id) "+op+" (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+}
+
+func (f GoodsFilter) cursorAt(good Good) GoodsCursor {
+	cursor := GoodsCursor{
+		SortBy: f.SortBy,
+		Desc:   f.Desc,
+		ID:     good.ID,
+	}
+
+	switch f.SortBy {
+	case GoodsSortPriority:
+		cursor.Priority = good.Priority
+	case GoodsSortName:
+		cursor.Name = good.Name
+	case GoodsSortCreatedAt:
+		cursor.CreatedAt = good.CreatedAt
+	}
+
+	return cursor
+}
+
+func (f GoodsFilter) sortColumn() string {
+	column, ok := goodsSortColumns[f.SortBy]
+	if !ok {
+		return "id"
+	}
+
+	return column
+}
+
+// countsKey однозначно описывает фильтры, от которых зависят счетчики, для ключа кеша
+func (f GoodsFilter) countsKey() string {
+	values := url.Values{}
+	if f.Removed != nil {
+		values.Set("removed", strconv.FormatBool(*f.Removed))
+	}
+	if f.Name != "" {
+		values.Set("name", f.Name)
+	}
+	if f.CreatedFrom != nil {
+		values.Set("from", f.CreatedFrom.UTC().Format(time.RFC3339Nano))
+	}
+	if f.CreatedTo != nil {
+		values.Set("to", f.CreatedTo.UTC().Format(time.RFC3339Nano))
+	}
+
+	return values.Encode()
+}
+
+// cacheKey - countsKey вместе с сортировкой, для ключа кеша страницы
+func (f GoodsFilter) cacheKey() string {
+	values, _ := url.ParseQuery(f.countsKey())
+	values.Set("sort", f.SortBy)
+	if f.Desc {
+		values.Set("desc", "true")
+	}
+
+	return values.Encode()
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGoodsFilterCursorAt(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	good := Good{ID: 7, Priority: 3, Name: "ёлка", CreatedAt: createdAt}
+
+	tests := []struct {
+		sortBy string
+		want   GoodsCursor
+	}{
+		{GoodsSortID, GoodsCursor{SortBy: GoodsSortID, ID: 7}},
+		{GoodsSortPriority, GoodsCursor{SortBy: GoodsSortPriority, ID: 7, Priority: 3}},
+		{GoodsSortName, GoodsCursor{SortBy: GoodsSortName, ID: 7, Name: "ёлка"}},
+		{GoodsSortCreatedAt, GoodsCursor{SortBy: GoodsSortCreatedAt, ID: 7, CreatedAt: createdAt}},
+	}
+
+	for _, test := range tests {
+		got := GoodsFilter{SortBy: test.sortBy, Desc: true}.cursorAt(good)
+		test.want.Desc = true
+		if got != test.want {
+			t.Errorf("sort [%s]: cursor [%+v], want [%+v]", test.sortBy, got, test.want)
+		}
+	}
+}
+
+func TestManyByCursorMismatch(t *testing.T) {
+	tests := []struct {
+		cursor GoodsCursor
+		filter GoodsFilter
+	}{
+		{GoodsCursor{SortBy: GoodsSortName}, GoodsFilter{SortBy: GoodsSortPriority}},
+		{GoodsCursor{SortBy: GoodsSortName}, GoodsFilter{SortBy: GoodsSortName, Desc: true}},
+		{GoodsCursor{SortBy: GoodsSortID, Desc: true}, GoodsFilter{SortBy: GoodsSortID}},
+	}
+
+	// несовпадение проверяется до запроса в базу
+	for _, test := range tests {
+		goods := GoodSlice{}
+		_, _, err := goods.ManyByCursor(1, 10, &test.cursor, test.filter)
+		if !errors.Is(err, ErrCursorMismatch) {
+			t.Errorf("cursor [%+v] with filter [%+v]: error [%v], want ErrCursorMismatch", test.cursor, test.filter, err)
+		}
+	}
+}
+
+func TestManyByCursorPagesWithTies(t *testing.T) {
+	openTestDB(t)
+	project := createTestProject(t)
+
+	// одна транзакция - одинаковый created_at у всех товаров, имена повторяются
+	goods := GoodSlice{}
+	for i := 0; i < 23; i++ {
+		goods = append(goods, Good{Name: fmt.Sprintf("good %d", i%3)})
+	}
+	err := goods.Create(project.ID)
+	if err != nil {
+		t.Fatalf("error creating goods [%s]", err.Error())
+	}
+
+	for _, sortBy := range []string{GoodsSortID, GoodsSortPriority, GoodsSortName, GoodsSortCreatedAt} {
+		for _, desc := range []bool{false, true} {
+			filter := GoodsFilter{SortBy: sortBy, Desc: desc}
+
+			want := GoodSlice{}
+			err := filter.order(postgresDB.Where("project_id = ?", project.ID)).Find(&want).Error
+			if err != nil {
+				t.Fatalf("error finding goods [%s]", err.Error())
+			}
+
+			got := []int{}
+			var cursor *GoodsCursor
+			for page := 0; ; page++ {
+				if page > len(want) {
+					t.Fatalf("sort [%s] desc [%t]: paging does not stop", sortBy, desc)
+				}
+
+				pageGoods := GoodSlice{}
+				_, next, err := pageGoods.ManyByCursor(project.ID, 4, cursor, filter)
+				if err != nil {
+					t.Fatalf("error getting page [%d] [%s]", page, err.Error())
+				}
+
+				for _, good := range pageGoods {
+					got = append(got, good.ID)
+				}
+
+				if next == nil {
+					break
+				}
+				cursor = next
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("sort [%s] desc [%t]: paged [%d] goods, want [%d]", sortBy, desc, len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i].ID {
+					t.Fatalf("sort [%s] desc [%t]: position [%d] has good [%d], want [%d]", sortBy, desc, i, got[i], want[i].ID)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// goodsPriorities возвращает приоритеты товаров проекта по возрастанию
+func goodsPriorities(t *testing.T, projectID int) []int {
+	t.Helper()
+
+	priorities := []int{}
+	err := postgresDB.Model(&Good{}).Where("project_id = ?", projectID).Order("priority").Pluck("priority", &priorities).Error
+	if err != nil {
+		t.Fatalf("error getting priorities of projectID=[%d] [%s]", projectID, err.Error())
+	}
+
+	return priorities
+}
+
+func TestGoodCreateConcurrentPriorities(t *testing.T) {
+	openTestDB(t)
+
+	const goodsPerProject = 200
+	projects := []Project{createTestProject(t), createTestProject(t)}
+
+	errs := make(chan error, goodsPerProject*len(projects))
+	wg := sync.WaitGroup{}
+	for i := 0; i < goodsPerProject; i++ {
+		for _, project := range projects {
+			wg.Add(1)
+			go func(projectID, i int) {
+				defer wg.Done()
+
+				good := Good{
+					ProjectID: projectID,
+					Name:      fmt.Sprintf("good %d", i),
+				}
+				errs <- good.Create()
+			}(project.ID, i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("error creating good [%s]", err.Error())
+		}
+	}
+
+	for _, project := range projects {
+		priorities := goodsPriorities(t, project.ID)
+		if len(priorities) != goodsPerProject {
+			t.Fatalf("projectID=[%d] has [%d] goods, want [%d]", project.ID, len(priorities), goodsPerProject)
+		}
+
+		// приоритеты уникальны и идут подряд с 1
+		for i, priority := range priorities {
+			if priority != i+1 {
+				t.Fatalf("projectID=[%d] priorities are not 1..%d: position [%d] has priority [%d]", project.ID, goodsPerProject, i, priority)
+			}
+		}
+	}
+}
+
+func TestGoodsCountsWithoutRemoved(t *testing.T) {
+	openTestDB(t)
+	project := createTestProject(t)
+
+	goods := GoodSlice{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	err := goods.Create(project.ID)
+	if err != nil {
+		t.Fatalf("error creating goods [%s]", err.Error())
+	}
+
+	removed := Good{ID: goods[0].ID, ProjectID: project.ID}
+	err = removed.Delete()
+	if err != nil {
+		t.Fatalf("error deleting good [%s]", err.Error())
+	}
+
+	// список по умолчанию скрывает удаленные, но счетчик удаленных должен их видеть
+	notRemoved := false
+	counts, err := goodsCounts(project.ID, GoodsFilter{Removed: &notRemoved})
+	if err != nil {
+		t.Fatalf("error counting goods [%s]", err.Error())
+	}
+
+	want := GoodsCounts{Total: 2, Removed: 1}
+	if counts != want {
+		t.Errorf("counts [%+v], want [%+v]", counts, want)
+	}
+}
+
+func TestGoodReprioritizeConcurrentWithCreate(t *testing.T) {
+	openTestDB(t)
+	project := createTestProject(t)
+
+	const initialGoods = 20
+	goods := make(GoodSlice, initialGoods)
+	for i := range goods {
+		goods[i].Name = fmt.Sprintf("initial %d", i)
+	}
+	err := goods.Create(project.ID)
+	if err != nil {
+		t.Fatalf("error creating goods [%s]", err.Error())
+	}
+
+	const rounds = 50
+	errs := make(chan error, 2*rounds)
+	wg := sync.WaitGroup{}
+	for i := 0; i < rounds; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+
+			good := Good{
+				ProjectID: project.ID,
+				Name:      fmt.Sprintf("created %d", i),
+			}
+			errs <- good.Create()
+		}(i)
+
+		go func(i int) {
+			defer wg.Done()
+
+			good := Good{ID: goods[i%initialGoods].ID, ProjectID: project.ID}
+			moved := GoodSlice{}
+			errs <- moved.Reprioritize(i%initialGoods+1, &good)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	// ожидание lock'а не должно заканчиваться нарушением уникальности или ошибкой сериализации
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("error creating or reprioritizing good [%s]", err.Error())
+		}
+	}
+
+	priorities := goodsPriorities(t, project.ID)
+	if len(priorities) != initialGoods+rounds {
+		t.Fatalf("project has [%d] goods, want [%d]", len(priorities), initialGoods+rounds)
+	}
+
+	for i := 1; i < len(priorities); i++ {
+		if priorities[i] == priorities[i-1] {
+			t.Fatalf("priority [%d] is not unique", priorities[i])
+		}
+	}
+}
+
+func TestGoodDeleteRestore(t *testing.T) {
+	openTestDB(t)
+	project := createTestProject(t)
+
+	good := Good{ProjectID: project.ID, Name: "a"}
+	err := good.Create()
+	if err != nil {
+		t.Fatalf("error creating good [%s]", err.Error())
+	}
+
+	for i := 0; i < 2; i++ {
+		deleted := Good{ID: good.ID, ProjectID: project.ID}
+		err = deleted.Delete()
+		if err != nil {
+			t.Fatalf("error deleting good [%s]", err.Error())
+		}
+
+		// повторное удаление ничего не меняет
+		if !deleted.Removed || deleted.RemovedAt == nil || deleted.Version != good.Version+1 {
+			t.Errorf("deleted good [%+v], want removed with version [%d]", deleted, good.Version+1)
+		}
+	}
+
+	restored := Good{ID: good.ID, ProjectID: project.ID}
+	err = restored.Restore()
+	if err != nil {
+		t.Fatalf("error restoring good [%s]", err.Error())
+	}
+	if restored.Removed || restored.RemovedAt != nil || restored.Version != good.Version+2 {
+		t.Errorf("restored good [%+v], want not removed with version [%d]", restored, good.Version+2)
+	}
+
+	missing := Good{ID: good.ID, ProjectID: project.ID + 1}
+	err = missing.Delete()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting good of another project returned [%v], want ErrNotFound", err)
+	}
+}
+
+func TestGoodUpsertKeepsDescriptionWhenAbsent(t *testing.T) {
+	openTestDB(t)
+	project := createTestProject(t)
+
+	externalKey := "tea"
+	description := "green"
+	good := Good{ProjectID: project.ID, ExternalKey: &externalKey, Name: "Tea"}
+	created, err := good.Upsert(&description, nil)
+	if err != nil || !created {
+		t.Fatalf("error creating good by upsert [%v], created [%t]", err, created)
+	}
+
+	// повторный импорт без описания меняет только название
+	renamed := Good{ProjectID: project.ID, ExternalKey: &externalKey, Name: "Green tea"}
+	created, err = renamed.Upsert(nil, nil)
+	if err != nil || created {
+		t.Fatalf("error updating good by upsert [%v], created [%t]", err, created)
+	}
+
+	if renamed.ID != good.ID || renamed.Name != "Green tea" || renamed.Description != description {
+		t.Errorf("upserted good [%+v], want id [%d] with name [Green tea] and description [%s]", renamed, good.ID, description)
+	}
+
+	empty := ""
+	cleared := Good{ProjectID: project.ID, ExternalKey: &externalKey, Name: "Green tea"}
+	_, err = cleared.Upsert(&empty, nil)
+	if err != nil {
+		t.Fatalf("error clearing description by upsert [%s]", err.Error())
+	}
+	if cleared.Description != "" {
+		t.Errorf("description [%s] is not cleared", cleared.Description)
+	}
+}
@@ -1,22 +1,334 @@
 package postgres
 
-import "github.com/sirupsen/logrus"
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ключ advisory lock, чтобы несколько реплик не мигрировали одновременно
+const migrationsLockKey = 72707369
+
+var errUnknownMigrationVersion = errors.New("unknown migration version")
+
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255)"`
+	AppliedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+const createSchemaMigrationsSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name varchar(255),
+	applied_at timestamptz DEFAULT CURRENT_TIMESTAMP
+)`
+
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
 
 func migrate() error {
 	logrus.Info("migrating tables...")
-	err := postgresDB.AutoMigrate(&Project{}, &Good{})
+	err := MigrateUp()
+	if err != nil {
+		logrus.Errorf("Error migrating up [%s]", err.Error())
+		return err
+	}
+
+	logrus.Info("successfully migrated needed migrations")
+	return nil
+}
+
+// MigrateUp применяет все непримененные миграции
+func MigrateUp() error {
+	migrations, err := loadMigrations()
 	if err != nil {
-		logrus.Errorf("Error initial migraion [%s]", err.Error())
 		return err
 	}
 
-	// TODO не сохранять если есть
-	err = ProjectCreate("Первая запись")
+	return migrateTo(migrations, migrations[len(migrations)-1].Version)
+}
+
+// MigrateDown откатывает последнюю примененную миграцию
+func MigrateDown() error {
+	migrations, err := loadMigrations()
 	if err != nil {
-		logrus.Errorf("Error creating project [%s]", err.Error())
 		return err
 	}
 
-	logrus.Info("successfully migrated needed migrations")
+	current, err := currentVersion()
+	if err != nil {
+		return err
+	}
+
+	if current == 0 {
+		logrus.Info("nothing to migrate down")
+		return nil
+	}
+
+	target := 0
+	for _, m := range migrations {
+		if m.Version < current {
+			target = m.Version
+		}
+	}
+
+	return migrateTo(migrations, target)
+}
+
+// MigrateTo применяет или откатывает миграции до указанной версии (0 - откатить все)
+func MigrateTo(version int) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	if version != 0 {
+		found := false
+		for _, m := range migrations {
+			if m.Version == version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			logrus.Errorf("migration with version [%d] not found", version)
+			return errUnknownMigrationVersion
+		}
+	}
+
+	return migrateTo(migrations, version)
+}
+
+func MigrationsStatus() ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(postgresDB)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	for _, m := range migrations {
+		status := MigrationStatus{
+			Version: m.Version,
+			Name:    m.Name,
+		}
+		if sm, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = sm.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func migrateTo(migrations []migration, target int) error {
+	for _, m := range migrations {
+		if m.Version > target {
+			break
+		}
+
+		err := applyMigration(m, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target {
+			break
+		}
+
+		err := applyMigration(m, false)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// applyMigration применяет (up=true) или откатывает миграцию в отдельной транзакции.
+// Если миграция уже в нужном состоянии, ничего не делает.
+func applyMigration(m migration, up bool) error {
+	return postgresDB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationsLockKey).Error
+		if err != nil {
+			logrus.Errorf("error locking migrations [%s]", err.Error())
+			return err
+		}
+
+		// таблица создается под lock'ом: одновременный CREATE TABLE из нескольких реплик падает даже с IF NOT EXISTS
+		err = tx.Exec(createSchemaMigrationsSQL).Error
+		if err != nil {
+			logrus.Errorf("error creating schema_migrations table [%s]", err.Error())
+			return err
+		}
+
+		applied, err := appliedMigrations(tx)
+		if err != nil {
+			return err
+		}
+
+		_, isApplied := applied[m.Version]
+		if isApplied == up {
+			return nil
+		}
+
+		sql := m.Down
+		if up {
+			sql = m.Up
+		}
+
+		err = tx.Exec(sql).Error
+		if err != nil {
+			logrus.Errorf("error applying migration [%d_%s] up=[%t] [%s]", m.Version, m.Name, up, err.Error())
+			return err
+		}
+
+		if up {
+			err = tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name}).Error
+		} else {
+			err = tx.Delete(&SchemaMigration{Version: m.Version}).Error
+		}
+		if err != nil {
+			logrus.Errorf("error saving migration [%d_%s] version [%s]", m.Version, m.Name, err.Error())
+			return err
+		}
+
+		logrus.Infof("successfully applied migration [%d_%s] up=[%t]", m.Version, m.Name, up)
+		return nil
+	})
+}
+
+func currentVersion() (int, error) {
+	// таблицы еще нет - ни одна миграция не применялась
+	if !postgresDB.Migrator().HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+
+	var version int
+	err := postgresDB.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		logrus.Errorf("error getting current migration version [%s]", err.Error())
+		return -1, err
+	}
+
+	return version, nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	schemaMigrations := []SchemaMigration{}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		err := db.Order("version").Find(&schemaMigrations).Error
+		if err != nil {
+			logrus.Errorf("error finding applied migrations [%s]", err.Error())
+			return nil, err
+		}
+	}
+
+	applied := map[int]SchemaMigration{}
+	for _, sm := range schemaMigrations {
+		applied[sm.Version] = sm
+	}
+
+	return applied, nil
+}
+
+// loadMigrations читает файлы вида 0001_name.up.sql / 0001_name.down.sql
+func loadMigrations() ([]migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		logrus.Errorf("error listing migration files [%s]", err.Error())
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, file := range files {
+		base := strings.TrimPrefix(file, "migrations/")
+
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("wrong migration file name [%s]", base)
+		}
+
+		versionPart, name, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("wrong migration file name [%s]", base)
+		}
+
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			logrus.Errorf("error convert migration version [%s] to int [%s]", versionPart, err.Error())
+			return nil, err
+		}
+
+		sql, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			logrus.Errorf("error reading migration file [%s] [%s]", file, err.Error())
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+
+		if direction == "up" {
+			m.Up = string(sql)
+		} else {
+			m.Down = string(sql)
+		}
+	}
+
+	migrations := []migration{}
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration [%d_%s] must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	if len(migrations) == 0 {
+		return nil, errors.New("no migrations found")
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
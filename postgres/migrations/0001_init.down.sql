@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS goods;
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS projects (
+	id         bigserial PRIMARY KEY,
+	name       varchar(100),
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS goods (
+	id          bigserial PRIMARY KEY,
+	project_id  bigint NOT NULL,
+	name        varchar(100),
+	description varchar(255),
+	priority    bigint,
+	removed     boolean DEFAULT false,
+	created_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_goods_project FOREIGN KEY (project_id) REFERENCES projects (id)
+);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS goods_notify_event ON goods;
+DROP FUNCTION IF EXISTS goods_notify_event();
@@ -0,0 +1,30 @@
+CREATE OR REPLACE FUNCTION goods_notify_event() RETURNS trigger AS $$
+DECLARE
+	good goods;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		good := OLD;
+	ELSE
+		good := NEW;
+	END IF;
+
+	PERFORM pg_notify('event', json_build_object(
+		'id', good.id,
+		'project_id', good.project_id,
+		'name', good.name,
+		'description', good.description,
+		'priority', good.priority,
+		'removed', good.removed,
+		'operation', TG_OP,
+		'timestamp', now()
+	)::text);
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS goods_notify_event ON goods;
+
+CREATE TRIGGER goods_notify_event
+	AFTER INSERT OR UPDATE OR DELETE ON goods
+	FOR EACH ROW EXECUTE FUNCTION goods_notify_event();
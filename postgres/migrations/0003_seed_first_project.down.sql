@@ -0,0 +1,3 @@
+DELETE FROM projects
+WHERE name = 'Первая запись'
+	AND NOT EXISTS (SELECT 1 FROM goods WHERE goods.project_id = projects.id);
@@ -0,0 +1,3 @@
+INSERT INTO projects (name)
+SELECT 'Первая запись'
+WHERE NOT EXISTS (SELECT 1 FROM projects WHERE name = 'Первая запись');
@@ -0,0 +1,3 @@
+ALTER TABLE goods DROP CONSTRAINT IF EXISTS goods_project_priority_unique;
+
+ALTER TABLE goods ALTER COLUMN priority DROP NOT NULL;
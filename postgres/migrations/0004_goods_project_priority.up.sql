@@ -0,0 +1,14 @@
+-- приоритеты раньше считались по всей таблице, перенумеровываем их внутри каждого проекта
+UPDATE goods
+SET priority = ranked.priority
+FROM (
+	SELECT id, row_number() OVER (PARTITION BY project_id ORDER BY priority, id) AS priority
+	FROM goods
+) AS ranked
+WHERE goods.id = ranked.id AND goods.priority IS DISTINCT FROM ranked.priority;
+
+ALTER TABLE goods ALTER COLUMN priority SET NOT NULL;
+
+ALTER TABLE goods
+	ADD CONSTRAINT goods_project_priority_unique UNIQUE (project_id, priority)
+	DEFERRABLE INITIALLY DEFERRED;
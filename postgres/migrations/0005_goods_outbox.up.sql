@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS goods_outbox (
+	id         bigserial PRIMARY KEY,
+	good_id    bigint NOT NULL,
+	project_id bigint NOT NULL,
+	payload    jsonb NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	sent_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS goods_outbox_pending_idx ON goods_outbox (id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS goods_outbox_sent_at_idx ON goods_outbox (sent_at);
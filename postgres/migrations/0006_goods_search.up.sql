@@ -0,0 +1,4 @@
+-- search_vector заполняет приложение с языком из POSTGRES_FTS_LANGUAGE
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE INDEX IF NOT EXISTS goods_search_vector_idx ON goods USING GIN (search_vector);
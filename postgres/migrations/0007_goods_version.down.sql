@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS goods_bump_version ON goods;
+DROP FUNCTION IF EXISTS goods_bump_version();
+
+ALTER TABLE goods DROP COLUMN IF EXISTS version;
@@ -0,0 +1,15 @@
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
+-- версия растет при любой записи полей товара, в том числе мимо api
+CREATE OR REPLACE FUNCTION goods_bump_version() RETURNS trigger AS $$
+BEGIN
+	NEW.version := OLD.version + 1;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS goods_bump_version ON goods;
+
+CREATE TRIGGER goods_bump_version
+	BEFORE UPDATE OF project_id, name, description, priority, removed ON goods
+	FOR EACH ROW EXECUTE FUNCTION goods_bump_version();
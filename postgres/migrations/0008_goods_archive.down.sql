@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS goods_archive;
+
+DROP INDEX IF EXISTS goods_removed_at_idx;
+
+ALTER TABLE goods DROP COLUMN IF EXISTS removed_at;
@@ -0,0 +1,20 @@
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS removed_at timestamptz;
+
+-- для уже удаленных товаров срок хранения отсчитывается с момента миграции
+UPDATE goods SET removed_at = CURRENT_TIMESTAMP WHERE removed AND removed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS goods_removed_at_idx ON goods (removed_at) WHERE removed;
+
+CREATE TABLE IF NOT EXISTS goods_archive (
+	id          bigint PRIMARY KEY,
+	project_id  bigint NOT NULL,
+	name        varchar(100),
+	description varchar(255),
+	priority    bigint,
+	created_at  timestamptz,
+	removed_at  timestamptz,
+	version     bigint,
+	purged_at   timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS goods_archive_project_id_idx ON goods_archive (project_id);
@@ -0,0 +1,5 @@
+ALTER TABLE goods_archive DROP COLUMN IF EXISTS external_key;
+
+DROP INDEX IF EXISTS goods_project_external_key_idx;
+
+ALTER TABLE goods DROP COLUMN IF EXISTS external_key;
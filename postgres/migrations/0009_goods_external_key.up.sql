@@ -0,0 +1,6 @@
+-- ключ товара во внешней системе, по нему импорт находит уже загруженные товары
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS external_key varchar(100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS goods_project_external_key_idx ON goods (project_id, external_key) WHERE external_key IS NOT NULL;
+
+ALTER TABLE goods_archive ADD COLUMN IF NOT EXISTS external_key varchar(100);
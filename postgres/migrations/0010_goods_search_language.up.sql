@@ -0,0 +1,2 @@
+-- конфигурация, с которой построен search_vector: при смене POSTGRES_FTS_LANGUAGE векторы пересчитываются
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS search_language text;
@@ -4,6 +4,7 @@ import (
 	"time"
 )
 
+type ProjectSlice []Project
 type Project struct {
 	ID        int       `gorm:"primaryKey"`
 	Name      string    `gorm:"type:varchar(100)"`
@@ -17,7 +18,10 @@ type Good struct {
 	Project     Project `gorm:"foreignKey:ProjectID"`
 	Name        string  `gorm:"type:varchar(100)"`
 	Description string  `gorm:"type:varchar(255)"`
+	ExternalKey *string `gorm:"type:varchar(100)"` // ключ товара во внешней системе, уникален в пределах проекта
 	Priority    int
-	Removed     bool      `gorm:"default:false"`
-	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	Removed     bool       `gorm:"default:false"`
+	RemovedAt   *time.Time // с этого момента отсчитывается срок хранения удаленного товара
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	Version     int        `gorm:"default:1"` // увеличивается триггером при каждой записи
 }
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"event"
+	"natsq"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// ключ advisory lock, чтобы outbox отправляла только одна реплика и порядок событий сохранялся
+	outboxLockKey = 72707370
+
+	outboxBatchSize    = 100
+	outboxPollInterval = 5 * time.Second
+	outboxRetention    = 7 * 24 * time.Hour
+)
+
+type GoodOutbox struct {
+	ID        int `gorm:"primaryKey"`
+	GoodID    int
+	ProjectID int
+	Payload   []byte `gorm:"type:jsonb"`
+	CreatedAt time.Time
+	SentAt    *time.Time
+}
+
+func (GoodOutbox) TableName() string {
+	return "goods_outbox"
+}
+
+var outboxWakeup = make(chan struct{}, 1)
+
+// writeOutbox сохраняет событие об изменении товара в той же транзакции, что и само изменение.
+// before пустой для созданного товара, after - для удаленного из базы
+func writeOutbox(tx *gorm.DB, eventType event.Type, before, after *Good) error {
+	goodEvent, err := event.NewGoodEvent(eventType, before.snapshot(), after.snapshot())
+	if err != nil {
+		logrus.Errorf("error making good event [%s]", err.Error())
+		return err
+	}
+
+	return saveOutboxEvent(tx, goodEvent)
+}
+
+// writeBatchOutbox сохраняет одно событие об изменении сразу нескольких товаров проекта
+func writeBatchOutbox(tx *gorm.DB, eventType event.Type, goods GoodSlice) error {
+	snapshots := make([]event.Good, 0, len(goods))
+	for i := range goods {
+		snapshots = append(snapshots, *goods[i].snapshot())
+	}
+
+	goodEvent, err := event.NewGoodsBatchEvent(eventType, snapshots)
+	if err != nil {
+		logrus.Errorf("error making goods batch event [%s]", err.Error())
+		return err
+	}
+
+	return saveOutboxEvent(tx, goodEvent)
+}
+
+func saveOutboxEvent(tx *gorm.DB, goodEvent event.GoodEvent) error {
+	payload, err := goodEvent.Encode()
+	if err != nil {
+		logrus.Errorf("error encoding good [%d] event [%s]", goodEvent.GoodID, err.Error())
+		return err
+	}
+
+	err = tx.Create(&GoodOutbox{
+		GoodID:    goodEvent.GoodID,
+		ProjectID: goodEvent.ProjectID,
+		Payload:   payload,
+	}).Error
+	if err != nil {
+		logrus.Errorf("error writing good [%d] event to outbox [%s]", goodEvent.GoodID, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (m *Good) snapshot() *event.Good {
+	if m == nil {
+		return nil
+	}
+
+	return &event.Good{
+		ID:          m.ID,
+		ProjectID:   m.ProjectID,
+		Name:        m.Name,
+		Description: m.Description,
+		Priority:    m.Priority,
+		Removed:     m.Removed,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// StartOutboxRelay запускает отправку событий из outbox в NATS.
+// Relay просыпается по уведомлению из postgres или раз в outboxPollInterval.
+func StartOutboxRelay() {
+	go func() {
+		ticker := time.NewTicker(outboxPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-outboxWakeup:
+			case <-ticker.C:
+				cleanOutbox()
+			}
+
+			relayOutbox()
+		}
+	}()
+}
+
+func wakeOutboxRelay() {
+	select {
+	case outboxWakeup <- struct{}{}:
+	default:
+	}
+}
+
+func relayOutbox() {
+	for {
+		sent, err := relayOutboxBatch()
+		if err != nil {
+			logrus.Errorf("error relaying outbox [%s]", err.Error())
+			return
+		}
+
+		if sent < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// relayOutboxBatch отправляет пачку событий по порядку. При ошибке отправки останавливается,
+// чтобы не нарушить порядок событий, недоставленные события уйдут при следующем запуске
+func relayOutboxBatch() (int, error) {
+	sent := 0
+	err := postgresDB.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockKey).Scan(&locked).Error
+		if err != nil {
+			logrus.Errorf("error locking outbox [%s]", err.Error())
+			return err
+		}
+
+		if !locked {
+			return nil
+		}
+
+		pending := []GoodOutbox{}
+		err = tx.Where("sent_at IS NULL").Order("id").Limit(outboxBatchSize).Find(&pending).Error
+		if err != nil {
+			logrus.Errorf("error finding pending outbox events [%s]", err.Error())
+			return err
+		}
+
+		// обработанные события: отправленные и пропущенные
+		sentIDs := []int{}
+		for _, outboxEvent := range pending {
+			goodEvent, err := event.Decode(outboxEvent.Payload)
+			if err != nil {
+				// событие старого формата или битое: пропускаем, иначе оно навсегда остановит relay
+				logrus.Errorf("error decoding outbox event [%d], skipping it [%s]", outboxEvent.ID, err.Error())
+				sentIDs = append(sentIDs, outboxEvent.ID)
+				continue
+			}
+
+			// id события - ключ дедупликации, повторная отправка после сбоя не задублирует событие
+			err = natsq.Publish(natsq.LogEventsSubject, outboxEvent.Payload, goodEvent.ID, goodEvent.Headers())
+			if err != nil {
+				logrus.Errorf("error publishing outbox event [%d] to NATS [%s]", outboxEvent.ID, err.Error())
+				break
+			}
+			sentIDs = append(sentIDs, outboxEvent.ID)
+		}
+
+		if len(sentIDs) == 0 {
+			return nil
+		}
+
+		err = tx.Model(&GoodOutbox{}).Where("id IN ?", sentIDs).Update("sent_at", time.Now()).Error
+		if err != nil {
+			logrus.Errorf("error marking outbox events as sent [%s]", err.Error())
+			return err
+		}
+
+		sent = len(sentIDs)
+		return nil
+	})
+
+	return sent, err
+}
+
+func cleanOutbox() {
+	err := postgresDB.Where("sent_at < ?", time.Now().Add(-outboxRetention)).Delete(&GoodOutbox{}).Error
+	if err != nil {
+		logrus.Errorf("error cleaning sent outbox events [%s]", err.Error())
+	}
+}
@@ -3,7 +3,6 @@ package postgres
 import (
 	"common"
 	"fmt"
-	"natsq"
 	"time"
 
 	"github.com/lib/pq"
@@ -15,28 +14,39 @@ import (
 var (
 	postgresDB *gorm.DB
 	listener   *pq.Listener
+
+	// конфигурация полнотекстового поиска postgres (russian, english, simple, ...)
+	searchLanguage string
+
+	// сколько хранить удаленные товары до окончательного удаления и как часто их чистить
+	purgeRetention time.Duration
+	purgeInterval  time.Duration
 )
 
+// ErrNotFound возвращается, когда запись не найдена
+var ErrNotFound = gorm.ErrRecordNotFound
+
 type connectionParams struct {
 	User     string
 	Password string
 	Host     string
 	Port     string
 	DBName   string
+
+	SearchLanguage string
+
+	PurgeRetention time.Duration
+	PurgeInterval  time.Duration
 }
 
 func OpenConnection(connParams connectionParams) (err error) {
-	logrus.Info("opening postgres connection...")
-
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", connParams.Host, connParams.User, connParams.Password, connParams.DBName, connParams.Port)
-	postgresDB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	err = Connect(connParams)
 	if err != nil {
-		logrus.Errorf("error opening postgres gorm connection [%s]", err.Error())
+		logrus.Errorf("error connecting to postgres [%s]", err.Error())
 		return err
 	}
-	logrus.Info("successfully opened postgres connection")
 
-	err = makeListener(dsn)
+	err = makeListener(connParams.dsn())
 	if err != nil {
 		logrus.Errorf("error making listener [%s]", err.Error())
 		return err
@@ -48,9 +58,36 @@ func OpenConnection(connParams connectionParams) (err error) {
 		return err
 	}
 
+	err = fillSearchVectors()
+	if err != nil {
+		logrus.Errorf("Error filling goods search vectors [%s]", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Connect только открывает соединение, без listener и миграций (нужно для подкоманды migrate)
+func Connect(connParams connectionParams) (err error) {
+	logrus.Info("opening postgres connection...")
+
+	searchLanguage = connParams.SearchLanguage
+	purgeRetention = connParams.PurgeRetention
+	purgeInterval = connParams.PurgeInterval
+	postgresDB, err = gorm.Open(postgres.Open(connParams.dsn()), &gorm.Config{})
+	if err != nil {
+		logrus.Errorf("error opening postgres gorm connection [%s]", err.Error())
+		return err
+	}
+	logrus.Info("successfully opened postgres connection")
+
 	return nil
 }
 
+func (connParams connectionParams) dsn() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", connParams.Host, connParams.User, connParams.Password, connParams.DBName, connParams.Port)
+}
+
 func makeListener(dsn string) error {
 	listener = pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
 		if err != nil {
@@ -74,12 +111,13 @@ func listen() {
 		case <-time.After(5 * time.Second):
 			logrus.Info("Waiting for notifications...")
 		case n := <-listener.Notify:
-			logrus.Infof("Received notification: [%s]", n.Extra)
-
-			err := natsq.NatsConn.Publish("log-events", []byte(n.Extra))
-			if err != nil {
-				logrus.Errorf("Error publishing to NATS [%s] [%s]", n.Extra, err.Error())
+			// сами события уходят в NATS через outbox, уведомление только будит relay.
+			// n == nil после переподключения listener: уведомления могли потеряться, проверяем outbox
+			if n != nil {
+				logrus.Infof("Received notification: [%s]", n.Extra)
 			}
+
+			wakeOutboxRelay()
 		}
 	}
 }
@@ -115,11 +153,36 @@ func GetConnectionParams() (connectionParams, error) {
 		return connectionParams{}, err
 	}
 
+	retentionParam := common.GetEnvVarDefault("POSTGRES_PURGE_RETENTION", "720h")
+	retention, err := time.ParseDuration(retentionParam)
+	if err == nil && retention <= 0 {
+		err = fmt.Errorf("duration must be positive")
+	}
+	if err != nil {
+		logrus.Errorf("error parsing POSTGRES_PURGE_RETENTION [%s] [%s]", retentionParam, err.Error())
+		return connectionParams{}, err
+	}
+
+	intervalParam := common.GetEnvVarDefault("POSTGRES_PURGE_INTERVAL", "1h")
+	interval, err := time.ParseDuration(intervalParam)
+	if err == nil && interval <= 0 {
+		err = fmt.Errorf("duration must be positive")
+	}
+	if err != nil {
+		logrus.Errorf("error parsing POSTGRES_PURGE_INTERVAL [%s] [%s]", intervalParam, err.Error())
+		return connectionParams{}, err
+	}
+
 	return connectionParams{
 		User:     user,
 		Password: password,
 		Host:     host,
 		Port:     port,
 		DBName:   dbName,
+
+		SearchLanguage: common.GetEnvVarDefault("POSTGRES_FTS_LANGUAGE", "russian"),
+
+		PurgeRetention: retention,
+		PurgeInterval:  interval,
 	}, nil
 }
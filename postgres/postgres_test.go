@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// тесты с базой запускаются только при заданном POSTGRES_TEST_DSN, например
+// POSTGRES_TEST_DSN="host=localhost user=postgres password=postgres dbname=hezzl_test port=5432 sslmode=disable"
+const testDSNEnv = "POSTGRES_TEST_DSN"
+
+var (
+	testDBOnce sync.Once
+	testDBErr  error
+)
+
+func openTestDB(t *testing.T) {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	testDBOnce.Do(func() {
+		searchLanguage = "simple"
+		purgeRetention = 30 * 24 * time.Hour
+		purgeInterval = time.Hour
+
+		postgresDB, testDBErr = gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if testDBErr != nil {
+			return
+		}
+
+		// конкурентные тесты не должны упираться в max_connections сервера
+		sqlDB, err := postgresDB.DB()
+		if err != nil {
+			testDBErr = err
+			return
+		}
+		sqlDB.SetMaxOpenConns(20)
+
+		testDBErr = MigrateUp()
+	})
+	if testDBErr != nil {
+		t.Fatalf("error opening test database [%s]", testDBErr.Error())
+	}
+}
+
+// createTestProject создает пустой проект, который удаляется вместе с товарами после теста
+func createTestProject(t *testing.T) Project {
+	t.Helper()
+
+	project, err := ProjectCreate(fmt.Sprintf("%s %d", t.Name(), time.Now().UnixNano()))
+	if err != nil {
+		t.Fatalf("error creating project [%s]", err.Error())
+	}
+
+	t.Cleanup(func() {
+		_, err := project.Delete()
+		if err != nil {
+			t.Errorf("error deleting project [%d] [%s]", project.ID, err.Error())
+		}
+	})
+
+	return project
+}
+
+func TestGetConnectionParamsPurgeDurations(t *testing.T) {
+	t.Setenv("POSTGRES_USER", "user")
+	t.Setenv("POSTGRES_PASSWORD", "password")
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_DB", "db")
+
+	tests := []struct {
+		retention string
+		interval  string
+		ok        bool
+	}{
+		{"720h", "1h", true},
+		{"0", "1h", false},
+		{"-1h", "1h", false},
+		{"720h", "0s", false},
+		{"720h", "-5m", false},
+		{"720h", "soon", false},
+	}
+
+	for _, test := range tests {
+		t.Setenv("POSTGRES_PURGE_RETENTION", test.retention)
+		t.Setenv("POSTGRES_PURGE_INTERVAL", test.interval)
+
+		_, err := GetConnectionParams()
+		if (err == nil) != test.ok {
+			t.Errorf("retention [%s], interval [%s]: error [%v], want ok=[%t]", test.retention, test.interval, err, test.ok)
+		}
+	}
+}
@@ -1,10 +1,102 @@
 package postgres
 
 import (
-	"fmt"
+	"github.com/sirupsen/logrus"
 )
 
-func ProjectCreate(name string) error {
-	sql := fmt.Sprintf("INSERT INTO projects (name) VALUES ('%s')", name)
-	return postgresDB.Exec(sql).Error
+// ProjectCreate создает проект. Имя передается в запрос параметром, а не строкой SQL
+func ProjectCreate(name string) (Project, error) {
+	project := Project{
+		Name: name,
+	}
+
+	err := postgresDB.Create(&project).Error
+	if err != nil {
+		logrus.Errorf("error creating project [%s]", err.Error())
+		return Project{}, err
+	}
+
+	return project, nil
+}
+
+func (m *Project) Get() error {
+	return postgresDB.First(m, m.ID).Error
+}
+
+func (m *Project) Update(name string) error {
+	err := postgresDB.First(m, m.ID).Error
+	if err != nil {
+		logrus.Errorf("error finding project by id=[%d] [%s]", m.ID, err.Error())
+		return err
+	}
+
+	m.Name = name
+
+	return postgresDB.Save(m).Error
+}
+
+// Delete удаляет проект вместе со всеми его товарами (включая помеченные removed).
+// Товары переносятся в goods_archive, как при очистке. Возвращает количество удаленных товаров.
+func (m *Project) Delete() (int64, error) {
+	tx := postgresDB.Begin()
+	if tx.Error != nil {
+		logrus.Errorf("error beginning transaction [%s]", tx.Error.Error())
+		return 0, tx.Error
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err := tx.Exec("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE").Error
+	if err != nil {
+		logrus.Errorf("error setting transaction level [%s]", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.First(m, m.ID).Error
+	if err != nil {
+		logrus.Errorf("error finding project by id=[%d] [%s]", m.ID, err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	goods := GoodSlice{}
+	err = tx.Where("project_id = ?", m.ID).Order("id").Find(&goods).Error
+	if err != nil {
+		logrus.Errorf("error finding goods of project with id=[%d] [%s]", m.ID, err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	// товары удаляются из базы совсем, как при очистке: попадают в архив с событием purged
+	err = archiveGoods(tx, goods)
+	if err != nil {
+		logrus.Errorf("error deleting goods of project with id=[%d] [%s]", m.ID, err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Delete(m).Error
+	if err != nil {
+		logrus.Errorf("error deleting project with id=[%d] [%s]", m.ID, err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		logrus.Errorf("error tx commit [%s]", err.Error())
+		return 0, err
+	}
+
+	invalidateGoodsCache(m.ID)
+	return int64(len(goods)), nil
+}
+
+func (m *ProjectSlice) Many() error {
+	return postgresDB.Order("id").Find(m).Error
 }
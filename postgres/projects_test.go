@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProjectCreateHostileNames(t *testing.T) {
+	openTestDB(t)
+
+	names := []string{
+		"'",
+		"'); DROP TABLE projects;--",
+		`\`,
+		"%_",
+		"Проект «Ёлка»",
+		strings.Repeat("я", 100),
+	}
+
+	for _, name := range names {
+		project, err := ProjectCreate(name)
+		if err != nil {
+			t.Fatalf("error creating project [%q] [%s]", name, err.Error())
+		}
+
+		got := Project{ID: project.ID}
+		err = got.Get()
+		if err != nil {
+			t.Fatalf("error getting project [%d] [%s]", project.ID, err.Error())
+		}
+
+		if got.Name != name {
+			t.Errorf("name [%q] came back as [%q]", name, got.Name)
+		}
+
+		_, err = got.Delete()
+		if err != nil {
+			t.Fatalf("error deleting project [%d] [%s]", project.ID, err.Error())
+		}
+	}
+
+	var table *string
+	err := postgresDB.Raw("SELECT to_regclass('projects')::text").Scan(&table).Error
+	if err != nil {
+		t.Fatalf("error checking projects table [%s]", err.Error())
+	}
+	if table == nil {
+		t.Fatal("projects table is gone")
+	}
+}
+
+func TestProjectDeleteArchivesGoods(t *testing.T) {
+	openTestDB(t)
+
+	project, err := ProjectCreate(t.Name())
+	if err != nil {
+		t.Fatalf("error creating project [%s]", err.Error())
+	}
+
+	goods := GoodSlice{{Name: "a"}, {Name: "b"}}
+	err = goods.Create(project.ID)
+	if err != nil {
+		t.Fatalf("error creating goods [%s]", err.Error())
+	}
+
+	removed := Good{ID: goods[0].ID, ProjectID: project.ID}
+	err = removed.Delete()
+	if err != nil {
+		t.Fatalf("error deleting good [%s]", err.Error())
+	}
+
+	deleted, err := project.Delete()
+	if err != nil {
+		t.Fatalf("error deleting project [%s]", err.Error())
+	}
+	if deleted != 2 {
+		t.Errorf("deleted [%d] goods, want 2", deleted)
+	}
+
+	var archived int64
+	err = postgresDB.Table("goods_archive").Where("project_id = ?", project.ID).Count(&archived).Error
+	if err != nil {
+		t.Fatalf("error counting archived goods [%s]", err.Error())
+	}
+	if archived != 2 {
+		t.Errorf("archived [%d] goods, want 2", archived)
+	}
+
+	// удаление вместе с проектом в журнале отличается от мягкого удаления
+	types := []string{}
+	err = postgresDB.Model(&GoodOutbox{}).
+		Where("project_id = ? AND payload->>'type' <> ?", project.ID, "batchCreated").
+		Order("id").
+		Pluck("payload->>'type'", &types).Error
+	if err != nil {
+		t.Fatalf("error reading outbox events [%s]", err.Error())
+	}
+
+	want := []string{"removed", "purged", "purged"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("events [%v], want [%v]", types, want)
+	}
+}
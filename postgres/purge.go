@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"event"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// ключ advisory lock, чтобы удаленные товары чистила только одна реплика
+	purgeLockKey = 72707371
+
+	purgeBatchSize = 500
+)
+
+var ErrPurgeRunning = errors.New("goods purge is already running")
+
+// StartPurgeWorker запускает окончательное удаление товаров, удаленных больше purgeRetention назад
+func StartPurgeWorker() {
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			purged, err := PurgeRemovedGoods()
+			if errors.Is(err, ErrPurgeRunning) {
+				continue
+			}
+			if err != nil {
+				logrus.Errorf("error purging removed goods after [%d] purged [%s]", purged, err.Error())
+				continue
+			}
+
+			if purged > 0 {
+				logrus.Infof("purged [%d] removed goods", purged)
+			}
+		}
+	}()
+}
+
+// PurgeRemovedGoods переносит товары, удаленные больше purgeRetention назад, в goods_archive
+// и удаляет их из goods. Возвращает ErrPurgeRunning, если очистку уже выполняет другая реплика.
+// При ошибке возвращает и количество товаров, удаленных до нее
+func PurgeRemovedGoods() (int, error) {
+	sqlDB, err := postgresDB.DB()
+	if err != nil {
+		logrus.Errorf("error getting sql db [%s]", err.Error())
+		return 0, err
+	}
+
+	// lock уровня сессии держится на отдельном соединении всю очистку, а не одну пачку:
+	// иначе между пачками его может перехватить другая реплика
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		logrus.Errorf("error getting connection for goods purge [%s]", err.Error())
+		return 0, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", purgeLockKey).Scan(&locked)
+	if err != nil {
+		logrus.Errorf("error locking goods purge [%s]", err.Error())
+		return 0, err
+	}
+
+	if !locked {
+		return 0, ErrPurgeRunning
+	}
+	defer unlockPurge(ctx, conn)
+
+	removedBefore := time.Now().Add(-purgeRetention)
+	total := 0
+	for {
+		purged, err := purgeRemovedGoodsBatch(removedBefore)
+		if err != nil {
+			return total, err
+		}
+
+		total += purged
+		if purged < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// unlockPurge снимает lock очистки. Если снять не удалось, соединение закрывается,
+// чтобы не вернуть в пул сессию, которая держит lock
+func unlockPurge(ctx context.Context, conn *sql.Conn) {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", purgeLockKey)
+	if err == nil {
+		return
+	}
+
+	logrus.Errorf("error unlocking goods purge [%s]", err.Error())
+	conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+}
+
+// purgeRemovedGoodsBatch удаляет одну пачку товаров, вызывается под lock'ом очистки
+func purgeRemovedGoodsBatch(removedBefore time.Time) (int, error) {
+	goods := GoodSlice{}
+	err := postgresDB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("removed AND removed_at < ?", removedBefore).
+			Order("id").
+			Limit(purgeBatchSize).
+			Find(&goods).Error
+		if err != nil {
+			logrus.Errorf("error finding goods to purge [%s]", err.Error())
+			return err
+		}
+
+		return archiveGoods(tx, goods)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	projects := map[int]bool{}
+	for _, good := range goods {
+		if !projects[good.ProjectID] {
+			projects[good.ProjectID] = true
+			invalidateGoodsCache(good.ProjectID)
+		}
+	}
+
+	return len(goods), nil
+}
+
+// archiveGoods переносит товары в goods_archive, удаляет их из goods и пишет о каждом событие purged.
+// Товары должны быть прочитаны в транзакции tx, снимки для событий берутся из них
+func archiveGoods(tx *gorm.DB, goods GoodSlice) error {
+	if len(goods) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(goods))
+	for _, good := range goods {
+		ids = append(ids, good.ID)
+	}
+
+	err := tx.Exec(`INSERT INTO goods_archive (id, project_id, external_key, name, description, priority, created_at, removed_at, version)
+		SELECT id, project_id, external_key, name, description, priority, created_at, removed_at, version FROM goods WHERE id IN ?
+		ON CONFLICT (id) DO NOTHING`, ids).Error
+	if err != nil {
+		logrus.Errorf("error archiving goods [%s]", err.Error())
+		return err
+	}
+
+	err = tx.Where("id IN ?", ids).Delete(&Good{}).Error
+	if err != nil {
+		logrus.Errorf("error deleting archived goods [%s]", err.Error())
+		return err
+	}
+
+	for i := range goods {
+		err = writeOutbox(tx, event.TypePurged, &goods[i], nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
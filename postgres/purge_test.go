@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPurgeRemovedGoodsHoldsLockForWholeRun(t *testing.T) {
+	openTestDB(t)
+
+	sqlDB, err := postgresDB.DB()
+	if err != nil {
+		t.Fatalf("error getting sql db [%s]", err.Error())
+	}
+
+	ctx := context.Background()
+	other, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("error getting connection [%s]", err.Error())
+	}
+	defer other.Close()
+
+	// пока lock держит другая реплика, очистка ничего не удаляет
+	var locked bool
+	err = other.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", purgeLockKey).Scan(&locked)
+	if err != nil || !locked {
+		t.Fatalf("error taking purge lock [%v], locked [%t]", err, locked)
+	}
+
+	purged, err := PurgeRemovedGoods()
+	if !errors.Is(err, ErrPurgeRunning) || purged != 0 {
+		t.Errorf("purge under foreign lock returned [%d] [%v], want 0 and ErrPurgeRunning", purged, err)
+	}
+
+	_, err = other.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", purgeLockKey)
+	if err != nil {
+		t.Fatalf("error unlocking purge lock [%s]", err.Error())
+	}
+
+	_, err = PurgeRemovedGoods()
+	if err != nil {
+		t.Fatalf("error purging removed goods [%s]", err.Error())
+	}
+
+	// после очистки lock снова свободен
+	err = other.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", purgeLockKey).Scan(&locked)
+	if err != nil || !locked {
+		t.Fatalf("purge lock is still held after purge [%v], locked [%t]", err, locked)
+	}
+
+	_, err = other.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", purgeLockKey)
+	if err != nil {
+		t.Fatalf("error unlocking purge lock [%s]", err.Error())
+	}
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const searchVectorSQL = "to_tsvector(?::regconfig, coalesce(name, '') || ' ' || coalesce(description, ''))"
+
+const searchHeadlineOptions = "StartSel=<b>, StopSel=</b>, MaxFragments=2, MaxWords=20, MinWords=5"
+
+// htmlEscapeSQL экранирует колонку до ts_headline, чтобы в подсветке HTML-разметкой были только теги <b>
+const htmlEscapeSQL = "replace(replace(replace(replace(replace(coalesce(%s, ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '\"', '&quot;'), '''', '&#39;')"
+
+type GoodSearchResult struct {
+	Good
+	Rank                 float64
+	NameHighlight        string
+	DescriptionHighlight string
+}
+
+type GoodSearchResultSlice []GoodSearchResult
+
+// Search ищет товары проекта по названию и описанию. Результаты отсортированы по релевантности.
+// NameHighlight и DescriptionHighlight - HTML: текст экранирован, найденные слова обернуты в <b></b>
+func (m *GoodSearchResultSlice) Search(projectID int, text string, includeRemoved bool, limit, offset int) (int, error) {
+	query := postgresDB.Table("goods, websearch_to_tsquery(?::regconfig, ?) AS query", searchLanguage, text).
+		Where("project_id = ? AND search_vector @@ query", projectID)
+	if !includeRemoved {
+		query = query.Where("removed = false")
+	}
+
+	var total int64
+	err := query.Session(&gorm.Session{}).Count(&total).Error
+	if err != nil {
+		logrus.Errorf("error counting found goods [%s]", err.Error())
+		return -1, err
+	}
+
+	err = query.
+		Select(
+			"goods.*, ts_rank_cd(search_vector, query) AS rank, "+
+				"ts_headline(?::regconfig, "+fmt.Sprintf(htmlEscapeSQL, "name")+", query, ?) AS name_highlight, "+
+				"ts_headline(?::regconfig, "+fmt.Sprintf(htmlEscapeSQL, "description")+", query, ?) AS description_highlight",
+			searchLanguage, searchHeadlineOptions, searchLanguage, searchHeadlineOptions,
+		).
+		Order("rank DESC").Order("id").
+		Limit(limit).Offset(offset).
+		Scan(&m).Error
+	if err != nil {
+		logrus.Errorf("error searching goods [%s]", err.Error())
+		return -1, err
+	}
+
+	return int(total), nil
+}
+
+// updateSearchVector пересчитывает поисковый вектор товара, вызывается в транзакции записи
+func updateSearchVector(tx *gorm.DB, goodIDs ...int) error {
+	sql := "UPDATE goods SET search_vector = " + searchVectorSQL + ", search_language = ? WHERE id IN ?"
+	err := tx.Exec(sql, searchLanguage, searchLanguage, goodIDs).Error
+	if err != nil {
+		logrus.Errorf("error updating search vector of goods %v [%s]", goodIDs, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// fillSearchVectors заполняет поисковые векторы товаров, созданных до появления поиска,
+// и пересчитывает векторы, построенные с другим POSTGRES_FTS_LANGUAGE
+func fillSearchVectors() error {
+	sql := "UPDATE goods SET search_vector = " + searchVectorSQL + ", search_language = ? " +
+		"WHERE search_vector IS NULL OR search_language IS DISTINCT FROM ?"
+	result := postgresDB.Exec(sql, searchLanguage, searchLanguage, searchLanguage)
+	if result.Error != nil {
+		logrus.Errorf("error filling search vectors [%s]", result.Error.Error())
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		logrus.Infof("filled search vectors of [%d] goods", result.RowsAffected)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchHighlightEscapesHTML(t *testing.T) {
+	openTestDB(t)
+	project := createTestProject(t)
+
+	good := Good{
+		ProjectID:   project.ID,
+		Name:        `<img src=x onerror=alert(1)> tea`,
+		Description: `green tea & "milk" <script>`,
+	}
+	err := good.Create()
+	if err != nil {
+		t.Fatalf("error creating good [%s]", err.Error())
+	}
+
+	results := GoodSearchResultSlice{}
+	total, err := results.Search(project.ID, "tea", false, 10, 0)
+	if err != nil {
+		t.Fatalf("error searching goods [%s]", err.Error())
+	}
+	if total != 1 || len(results) != 1 {
+		t.Fatalf("found [%d] goods, want 1", total)
+	}
+
+	for _, want := range []string{"&lt;img", "<b>tea</b>"} {
+		if !strings.Contains(results[0].NameHighlight, want) {
+			t.Errorf("name highlight [%s] has no [%s]", results[0].NameHighlight, want)
+		}
+	}
+
+	for _, tag := range []string{"<img", "<script"} {
+		if strings.Contains(results[0].NameHighlight+results[0].DescriptionHighlight, tag) {
+			t.Errorf("highlight contains raw [%s]: [%s] [%s]", tag, results[0].NameHighlight, results[0].DescriptionHighlight)
+		}
+	}
+}
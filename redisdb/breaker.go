@@ -0,0 +1,100 @@
+package redisdb
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	breakerFailureThreshold = 5
+	breakerCooldown         = 30 * time.Second
+)
+
+var (
+	ErrUnavailable = errors.New("redis is unavailable")
+	ErrNil         = redis.Nil
+)
+
+// счетчики не публикуются в общий expvar, наружу их отдает только Metrics
+var (
+	cacheReadErrors  = new(expvar.Int)
+	cacheWriteErrors = new(expvar.Int)
+	breakerOpenings  = new(expvar.Int)
+)
+
+// Metrics возвращает счетчики ошибок redis и открытий breaker'а. Запросы, не пропущенные
+// открытым breaker'ом, ошибками не считаются: в redis они не ходили
+func Metrics() map[string]int64 {
+	return map[string]int64{
+		"redis_cache_read_errors":  cacheReadErrors.Value(),
+		"redis_cache_write_errors": cacheWriteErrors.Value(),
+		"redis_breaker_openings":   breakerOpenings.Value(),
+	}
+}
+
+// breaker - простой circuit breaker: после breakerFailureThreshold ошибок подряд
+// перестает ходить в redis на breakerCooldown, потом пропускает один пробный запрос
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+var breaker = &circuitBreaker{}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerFailureThreshold {
+		return true
+	}
+
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if err == nil || err == redis.Nil {
+		if b.failures >= breakerFailureThreshold {
+			logrus.Info("redis is available again, closing circuit breaker")
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= breakerFailureThreshold {
+		if b.failures == breakerFailureThreshold {
+			breakerOpenings.Add(1)
+			logrus.Errorf("redis failed [%d] times in a row, opening circuit breaker for [%s]", b.failures, breakerCooldown)
+		}
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
+
+// call выполняет запрос к redis через breaker
+func call(fn func() error) error {
+	if RedisClient == nil || !breaker.allow() {
+		return ErrUnavailable
+	}
+
+	err := fn()
+	breaker.record(err)
+	return err
+}
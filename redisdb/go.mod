@@ -2,17 +2,30 @@ module redisdb
 
 go 1.21
 
-require common v0.0.0-00010101000000-000000000000
+require (
+	common v0.0.0-00010101000000-000000000000
+	natsq v0.0.0-00010101000000-000000000000
+)
 
 require (
 	github.com/go-redis/redis v6.15.9+incompatible
+	github.com/nats-io/nats.go v1.33.1
 	github.com/sirupsen/logrus v1.9.3
 )
 
 require (
+	event v0.0.0-00010101000000-000000000000
+	github.com/klauspost/compress v1.17.2 // indirect
+	github.com/nats-io/nkeys v0.4.7 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
 	github.com/onsi/ginkgo v1.16.5 // indirect
 	github.com/onsi/gomega v1.31.1 // indirect
-	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/crypto v0.18.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
 )
 
 replace common => ../common
+
+replace natsq => ../natsq
+
+replace event => ../event
@@ -0,0 +1,41 @@
+package redisdb
+
+import (
+	"event"
+	"natsq"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+// StartInvalidator сбрасывает кеш проекта по каждому событию изменения товара из NATS.
+// Так кеш остается согласованным, даже если товар изменила другая реплика api или запрос мимо api.
+func StartInvalidator() error {
+	// у каждой реплики свой эфемерный consumer: старые события кеш уже пережил, читаем только новые
+	_, err := natsq.JetStream.Subscribe(
+		natsq.LogEventsSubject,
+		invalidateByEvent,
+		nats.BindStream(natsq.GoodsStreamName),
+		nats.DeliverNew(),
+		nats.AckNone(),
+	)
+	if err != nil {
+		logrus.Errorf("error subscribing to [%s] [%s]", natsq.LogEventsSubject, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func invalidateByEvent(msg *nats.Msg) {
+	goodEvent, err := event.Decode(msg.Data)
+	if err != nil {
+		logrus.Errorf("error decoding good event [%s] [%s]", msg.Data, err.Error())
+		return
+	}
+
+	err = InvalidateGoods(goodEvent.ProjectID)
+	if err != nil {
+		logrus.Errorf("error invalidating goods cache of projectID=[%d] by good [%d] event [%s]", goodEvent.ProjectID, goodEvent.GoodID, err.Error())
+	}
+}
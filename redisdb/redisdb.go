@@ -10,7 +10,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-const AllGoodsKey = "all_goods"
+const cacheTTL = time.Minute
 
 var RedisClient *redis.Client
 
@@ -27,7 +27,10 @@ func OpenConnection(connParams connectionParams) error {
 		DB:       connParams.DB,
 	})
 
-	_, err := RedisClient.Ping().Result()
+	// клиент остается рабочим даже при ошибке: он переподключится сам, когда redis поднимется
+	err := call(func() error {
+		return RedisClient.Ping().Err()
+	})
 	if err != nil {
 		logrus.Errorf("error pinging redis connection [%s]", err.Error())
 		return err
@@ -56,6 +59,35 @@ func GetConnectionParams() (connectionParams, error) {
 	}, nil
 }
 
+func GoodKey(projectID, id int) string {
+	return fmt.Sprintf("good_%d_%d", projectID, id)
+}
+
+// GoodsCountsKey - ключ счетчиков товаров проекта. filterKey - канонический вид фильтров списка,
+// у каждой комбинации фильтров свои счетчики
+func GoodsCountsKey(projectID int, filterKey string) string {
+	return fmt.Sprintf("goods_counts_%d_%s", projectID, filterKey)
+}
+
+// GoodsKey - ключ страницы списка товаров проекта, filterKey здесь включает и сортировку
+func GoodsKey(projectID, limit, offset int, filterKey string) string {
+	return fmt.Sprintf("goods_%d_%d_%d_%s", projectID, limit, offset, filterKey)
+}
+
+// Get возвращает закешированное значение. ErrNil означает промах, ErrUnavailable - что breaker открыт
+func Get(key string) ([]byte, error) {
+	var data []byte
+	err := call(func() (err error) {
+		data, err = RedisClient.Get(key).Bytes()
+		return err
+	})
+	if err != nil && err != redis.Nil && err != ErrUnavailable {
+		cacheReadErrors.Add(1)
+	}
+
+	return data, err
+}
+
 func Cache(key string, data interface{}) error {
 	dataJSON, err := json.Marshal(data)
 	if err != nil {
@@ -63,5 +95,74 @@ func Cache(key string, data interface{}) error {
 		return err
 	}
 
-	return RedisClient.Set(key, dataJSON, time.Minute).Err()
+	err = call(func() error {
+		return RedisClient.Set(key, dataJSON, cacheTTL).Err()
+	})
+	if err != nil && err != ErrUnavailable {
+		cacheWriteErrors.Add(1)
+	}
+
+	return err
+}
+
+// CacheGoods кеширует данные по товарам проекта и запоминает ключ в множестве ключей проекта,
+// чтобы InvalidateGoods мог их все удалить
+func CacheGoods(projectID int, key string, data interface{}) error {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		logrus.Errorf("error marshal data [%s]", err.Error())
+		return err
+	}
+
+	keysKey := goodsKeysKey(projectID)
+	err = call(func() error {
+		pipe := RedisClient.TxPipeline()
+		pipe.Set(key, dataJSON, cacheTTL)
+		pipe.SAdd(keysKey, key)
+		pipe.Expire(keysKey, cacheTTL)
+
+		_, err := pipe.Exec()
+		return err
+	})
+	if err != nil && err != ErrUnavailable {
+		cacheWriteErrors.Add(1)
+	}
+
+	return err
+}
+
+// InvalidateGoods удаляет все закешированные ключи товаров проекта
+func InvalidateGoods(projectID int) error {
+	keysKey := goodsKeysKey(projectID)
+	keys := []string{}
+	err := call(func() (err error) {
+		keys, err = RedisClient.SMembers(keysKey).Result()
+		return err
+	})
+	if err != nil {
+		if err != ErrUnavailable {
+			cacheWriteErrors.Add(1)
+		}
+		logrus.Errorf("error getting cached keys of projectID=[%d] [%s]", projectID, err.Error())
+		return err
+	}
+
+	keys = append(keys, keysKey)
+	err = call(func() error {
+		return RedisClient.Del(keys...).Err()
+	})
+	if err != nil {
+		if err != ErrUnavailable {
+			cacheWriteErrors.Add(1)
+		}
+		logrus.Errorf("error deleting cached keys of projectID=[%d] [%s]", projectID, err.Error())
+		return err
+	}
+
+	logrus.Infof("invalidated [%d] cached keys of projectID=[%d]", len(keys)-1, projectID)
+	return nil
+}
+
+func goodsKeysKey(projectID int) string {
+	return fmt.Sprintf("goods_keys_%d", projectID)
 }